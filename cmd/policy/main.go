@@ -6,14 +6,11 @@ import (
 
 	"github.com/alecthomas/kong"
 	"github.com/opcr-io/policy/pkg/cmd"
+	perr "github.com/opcr-io/policy/pkg/errors"
+	"github.com/opcr-io/policy/pkg/output"
 	"github.com/pkg/errors"
 )
 
-const (
-	rcOK  int = 0
-	rcErr int = 1
-)
-
 func main() {
 	if len(os.Args) == 1 {
 		os.Args = append(os.Args, "--help")
@@ -22,9 +19,20 @@ func main() {
 	os.Exit(Run())
 }
 
-func exitErr(err error) int {
+func exitErr(printer *output.Printer, err error) int {
 	fmt.Fprintln(os.Stderr, err.Error())
-	return rcErr
+
+	exitCode := perr.ExitCode(err)
+
+	if printer.Structured() {
+		_ = printer.Print(&output.ErrorDocument{Error: output.ErrorDetail{
+			Message:  err.Error(),
+			Code:     string(perr.CodeOf(err)),
+			ExitCode: exitCode,
+		}})
+	}
+
+	return exitCode
 }
 
 func Run() int {
@@ -32,7 +40,7 @@ func Run() int {
 
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return exitErr(errors.Wrap(err, "failed to determine user home directory"))
+		return exitErr(nil, errors.Wrap(err, "failed to determine user home directory"))
 	}
 
 	kongCtx := kong.Parse(
@@ -55,20 +63,32 @@ func Run() int {
 		},
 	)
 
+	printer, err := output.New(os.Stdout, cli.Format)
+	if err != nil {
+		return exitErr(nil, err)
+	}
+
+	// SARIF logs hold findings, only lint reports them.
+	if printer.SARIF() && kongCtx.Selected().Name != "lint" {
+		return exitErr(nil, errors.Errorf("the %s format is only supported by lint", output.FormatSARIF))
+	}
+
 	g := &cmd.Globals{
 		Debug:     cli.Debug,
 		Config:    cli.Config,
 		Verbosity: cli.Verbosity,
 		Insecure:  cli.Insecure,
 		Plaintext: cli.Plaintext,
+		Quiet:     cli.Quiet,
+		Output:    printer,
 	}
 
 	cleanup := g.Setup()
 	defer cleanup()
 
 	if err := kongCtx.Run(g); err != nil {
-		return exitErr(err)
+		return exitErr(printer, err)
 	}
 
-	return rcOK
+	return perr.ExitOK
 }
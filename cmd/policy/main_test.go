@@ -19,7 +19,8 @@ import (
 
 func TestMain(m *testing.M) {
 	testscript.Main(m, map[string]func(){
-		"policy": main,
+		"policy":           main,
+		"builtin-provider": builtinProvider,
 	})
 }
 
@@ -27,6 +28,26 @@ var scripts = []string{
 	"tests/cli/001-version.txtar",
 	"tests/cli/002-fixtures.txtar",
 	"tests/cli/003-build-policy_v1.txtar",
+	"tests/cli/004-format.txtar",
+	"tests/cli/005-eval.txtar",
+	"tests/cli/006-build-tags.txtar",
+	"tests/cli/007-reproducible.txtar",
+	"tests/cli/008-build-cache.txtar",
+	"tests/cli/009-build-watch.txtar",
+	"tests/cli/010-builtins-scan.txtar",
+	"tests/cli/011-mocks.txtar",
+	"tests/cli/012-builtin-providers.txtar",
+	"tests/cli/013-migrate.txtar",
+	"tests/cli/014-fmt-lint.txtar",
+	"tests/cli/015-diff.txtar",
+	"tests/cli/016-replay.txtar",
+	"tests/cli/017-unpack.txtar",
+	"tests/cli/018-doc.txtar",
+	"tests/cli/019-entrypoints.txtar",
+	"tests/cli/020-schemas.txtar",
+	"tests/cli/021-capability-profiles.txtar",
+	"tests/cli/022-check.txtar",
+	"tests/cli/023-coverage.txtar",
 	"tests/cli/100-templates-list.txtar",
 	"tests/cli/101-template-apply.txtar",
 }
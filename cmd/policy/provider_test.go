@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// builtinProvider is a builtin provider for the scripts, it implements acme.greet/1, acme.add/2 and
+// acme.fail/1, which always returns an error.
+func builtinProvider() {
+	type request struct {
+		ID     int    `json:"id"`
+		Method string `json:"method"`
+		Params struct {
+			Name string `json:"name"`
+			Args []any  `json:"args"`
+		} `json:"params"`
+	}
+
+	anyFunc := func(name string, arity int) map[string]any {
+		args := make([]map[string]string, arity)
+		for i := range args {
+			args[i] = map[string]string{"type": "any"}
+		}
+
+		return map[string]any{
+			"name": name,
+			"decl": map[string]any{"type": "function", "args": args, "result": map[string]string{"type": "any"}},
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	scanner := bufio.NewScanner(os.Stdin)
+
+	for scanner.Scan() {
+		req := request{}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		resp := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+
+		switch {
+		case req.Method == "builtins.declare":
+			resp["result"] = map[string]any{"builtins": []any{
+				anyFunc("acme.greet", 1),
+				anyFunc("acme.add", 2),
+				anyFunc("acme.fail", 1),
+			}}
+		case req.Params.Name == "acme.greet":
+			resp["result"] = map[string]any{"value": fmt.Sprintf("hello, %v", req.Params.Args[0])}
+		case req.Params.Name == "acme.add":
+			a, _ := req.Params.Args[0].(float64)
+			b, _ := req.Params.Args[1].(float64)
+			resp["result"] = map[string]any{"value": a + b}
+		default:
+			resp["error"] = map[string]any{"code": -32000, "message": "directory unavailable"}
+		}
+
+		if err := enc.Encode(resp); err != nil {
+			os.Exit(1)
+		}
+	}
+}
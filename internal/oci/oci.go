@@ -29,6 +29,7 @@ type Oci struct {
 	hostsFunc      docker.RegistryHosts
 	ociStore       *oci.Store
 	policyRootPath string
+	progress       *progressTrackers
 }
 
 func NewOCI(ctx context.Context, log *zerolog.Logger, hostsFunc docker.RegistryHosts, policyRoot string) (*Oci, error) {
@@ -88,7 +89,9 @@ func (o *Oci) Pull(ref string) (digest.Digest, error) {
 		return nil
 	}
 
-	if _, err := oras.Copy(o.ctx, remoteManager, ref, o.ociStore, "", opts); err != nil {
+	src, opts := o.withProgress(remoteManager, opts)
+
+	if _, err := oras.Copy(o.ctx, src, ref, o.ociStore, "", opts); err != nil {
 		return "", errors.Wrap(err, "oras pull failed")
 	}
 
@@ -129,11 +132,14 @@ func (o *Oci) ListReferences() (map[string]v1.Descriptor, error) {
 	return refs, nil
 }
 
-func (o *Oci) Push(ref string) (digest.Digest, error) {
+// Push uploads the image tagged with ref to its remote repository.
+// Blobs missing from the target repository are mounted from any of the mountFrom repositories
+// in the same registry that already hold them, rather than uploaded again.
+func (o *Oci) Push(ref string, mountFrom []string) (digest.Digest, error) {
 	dockerResolver := docker.NewResolver(docker.ResolverOptions{
 		Hosts: o.hostsFunc,
 	})
-	remoteManager := &remoteManager{resolver: dockerResolver, srcRef: ref, fetcher: o.ociStore}
+	remoteManager := &remoteManager{resolver: dockerResolver, srcRef: ref, fetcher: o.ociStore, mountFrom: mountFrom}
 
 	descriptor, err := o.ociStore.Resolve(o.ctx, ref)
 	if err != nil {
@@ -158,6 +164,19 @@ func (o *Oci) Push(ref string) (digest.Digest, error) {
 		return "", err
 	}
 
+	// the reference is moved to each blob while it is copied, point it back at the manifest when a copy fails.
+	restored := false
+
+	defer func() {
+		if restored {
+			return
+		}
+
+		if err := o.ociStore.Tag(o.ctx, descriptor, ref); err != nil {
+			o.logger.Error().Err(err).Str("ref", ref).Msg("Failed to restore the local manifest tag.")
+		}
+	}()
+
 	// tag tarball
 	err = o.ociStore.Tag(o.ctx, *tarBallDescriptor, ref)
 	if err != nil {
@@ -165,7 +184,7 @@ func (o *Oci) Push(ref string) (digest.Digest, error) {
 	}
 
 	// copy tarball to remote first
-	if _, err := oras.Copy(o.ctx, o.ociStore, ref, remoteManager, "", oras.DefaultCopyOptions); err != nil {
+	if _, err := o.copy(o.ociStore, ref, remoteManager); err != nil {
 		return "", errors.Wrap(err, "oras push tarball failed")
 	}
 
@@ -182,7 +201,7 @@ func (o *Oci) Push(ref string) (digest.Digest, error) {
 	}
 
 	// copy config to remote
-	if _, err := oras.Copy(o.ctx, o.ociStore, ref, remoteManager, "", oras.DefaultCopyOptions); err != nil {
+	if _, err := o.copy(o.ociStore, ref, remoteManager); err != nil {
 		return "", errors.Wrap(err, "oras push config failed")
 	}
 
@@ -198,8 +217,10 @@ func (o *Oci) Push(ref string) (digest.Digest, error) {
 		return "", err
 	}
 
+	restored = true
+
 	// copy manifest to remote
-	if _, err := oras.Copy(o.ctx, o.ociStore, ref, remoteManager, "", oras.DefaultCopyOptions); err != nil {
+	if _, err := o.copy(o.ociStore, ref, remoteManager); err != nil {
 		return "", errors.Wrap(err, "oras push manifest failed")
 	}
 
@@ -330,7 +351,7 @@ func (o *Oci) pushBasedOnTarBall(remoteManager *remoteManager, desc *v1.Descript
 		return "", err
 	}
 
-	if _, err := oras.Copy(o.ctx, o.ociStore, ref, remoteManager, "", oras.DefaultCopyOptions); err != nil {
+	if _, err := o.copy(o.ociStore, ref, remoteManager); err != nil {
 		return "", errors.Wrap(err, "oras push failed")
 	}
 
@@ -339,7 +360,7 @@ func (o *Oci) pushBasedOnTarBall(remoteManager *remoteManager, desc *v1.Descript
 	}
 
 	remoteManager.fetcher = memoryStore
-	if _, err := oras.Copy(o.ctx, memoryStore, ref, remoteManager, "", oras.DefaultCopyOptions); err != nil {
+	if _, err := o.copy(memoryStore, ref, remoteManager); err != nil {
 		return "", errors.Wrap(err, "oras push manifest failed")
 	}
 
@@ -347,13 +368,20 @@ func (o *Oci) pushBasedOnTarBall(remoteManager *remoteManager, desc *v1.Descript
 		return "", err
 	}
 
-	if _, err := oras.Copy(o.ctx, memoryStore, ref, remoteManager, "", oras.DefaultCopyOptions); err != nil {
+	if _, err := o.copy(memoryStore, ref, remoteManager); err != nil {
 		return "", errors.Wrap(err, "oras push manifest failed")
 	}
 
 	return desc.Digest, nil
 }
 
+// copy copies the graph tagged with ref from src to dst, reporting transfer progress if enabled.
+func (o *Oci) copy(src oras.ReadOnlyTarget, ref string, dst oras.Target) (v1.Descriptor, error) {
+	source, opts := o.withProgress(src, oras.DefaultCopyOptions)
+
+	return oras.Copy(o.ctx, source, ref, dst, "", opts)
+}
+
 func cloneDescriptor(desc *v1.Descriptor) (v1.Descriptor, error) {
 	b, err := json.Marshal(desc)
 	if err != nil {
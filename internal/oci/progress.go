@@ -0,0 +1,143 @@
+package oci
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+)
+
+// Progress tracks the transfer of a single blob.
+type Progress interface {
+	// Add records n more bytes as transferred.
+	Add(n int64)
+	// Done marks the transfer as completed.
+	Done()
+	// Skip marks the transfer as not needed, e.g. because the content already exists.
+	Skip()
+}
+
+// ProgressFunc starts tracking the transfer of the given descriptor.
+type ProgressFunc func(desc v1.Descriptor) Progress
+
+// NopProgress is a Progress reporting nothing.
+type NopProgress struct{}
+
+func (NopProgress) Add(int64) {}
+func (NopProgress) Done()     {}
+func (NopProgress) Skip()     {}
+
+// SetProgress registers fn to report the progress of blob transfers during pull and push.
+func (o *Oci) SetProgress(fn ProgressFunc) {
+	o.progress = &progressTrackers{start: fn, trackers: map[digest.Digest]Progress{}}
+}
+
+// NewProgressReader returns a reader that reports every byte read from rc to p.
+func NewProgressReader(rc io.ReadCloser, p Progress) io.ReadCloser {
+	return &progressReader{ReadCloser: rc, progress: p}
+}
+
+type progressReader struct {
+	io.ReadCloser
+	progress Progress
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.progress.Add(int64(n))
+
+	return n, err
+}
+
+// progressTrackers holds one tracker per blob, shared by all copies of an operation
+// so that a blob pushed by an earlier copy is not reported again when a later one skips it.
+type progressTrackers struct {
+	mu       sync.Mutex
+	start    ProgressFunc
+	trackers map[digest.Digest]Progress
+}
+
+// progressSource reports the bytes fetched from the copy source to the tracker of each descriptor.
+type progressSource struct {
+	oras.ReadOnlyTarget
+
+	*progressTrackers
+}
+
+func (s *progressSource) Fetch(ctx context.Context, target v1.Descriptor) (io.ReadCloser, error) {
+	rc, err := s.ReadOnlyTarget.Fetch(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	tracker, ok := s.trackers[target.Digest]
+	s.mu.Unlock()
+
+	if !ok {
+		return rc, nil
+	}
+
+	return NewProgressReader(rc, tracker), nil
+}
+
+func (s *progressTrackers) tracker(desc v1.Descriptor) Progress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tracker, ok := s.trackers[desc.Digest]
+	if !ok {
+		tracker = s.start(desc)
+		s.trackers[desc.Digest] = tracker
+	}
+
+	return tracker
+}
+
+// withProgress hooks the registered ProgressFunc, if any, into a copy from src using opts.
+func (o *Oci) withProgress(src oras.ReadOnlyTarget, opts oras.CopyOptions) (oras.ReadOnlyTarget, oras.CopyOptions) {
+	if o.progress == nil {
+		return src, opts
+	}
+
+	source := &progressSource{ReadOnlyTarget: src, progressTrackers: o.progress}
+
+	preCopy, postCopy, onCopySkipped := opts.PreCopy, opts.PostCopy, opts.OnCopySkipped
+
+	opts.PreCopy = func(ctx context.Context, desc v1.Descriptor) error {
+		if preCopy != nil {
+			if err := preCopy(ctx, desc); err != nil {
+				return err
+			}
+		}
+
+		source.tracker(desc)
+
+		return nil
+	}
+
+	opts.PostCopy = func(ctx context.Context, desc v1.Descriptor) error {
+		source.tracker(desc).Done()
+
+		if postCopy != nil {
+			return postCopy(ctx, desc)
+		}
+
+		return nil
+	}
+
+	opts.OnCopySkipped = func(ctx context.Context, desc v1.Descriptor) error {
+		source.tracker(desc).Skip()
+
+		if onCopySkipped != nil {
+			return onCopySkipped(ctx, desc)
+		}
+
+		return nil
+	}
+
+	return source, opts
+}
@@ -4,17 +4,23 @@ import (
 	"bufio"
 	"context"
 	"io"
+	"maps"
 	"strings"
 
+	"github.com/containerd/containerd/v2/core/images"
 	"github.com/containerd/containerd/v2/core/remotes"
+	"github.com/containerd/containerd/v2/pkg/labels"
+	"github.com/containerd/errdefs"
+	"github.com/distribution/reference"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"oras.land/oras-go/v2/content"
 )
 
 type remoteManager struct {
-	resolver remotes.Resolver
-	fetcher  content.Fetcher
-	srcRef   string
+	resolver  remotes.Resolver
+	fetcher   content.Fetcher
+	srcRef    string
+	mountFrom []string
 }
 
 func (r *remoteManager) Resolve(ctx context.Context, ref string) (v1.Descriptor, error) {
@@ -35,8 +41,28 @@ func (r *remoteManager) Fetch(ctx context.Context, target v1.Descriptor) (io.Rea
 	return fetcher.Fetch(ctx, target)
 }
 
+// Exists checks whether the target is present in the remote repository using HEAD requests only.
 func (r *remoteManager) Exists(ctx context.Context, target v1.Descriptor) (bool, error) {
-	if _, err := r.Fetch(ctx, target); err != nil {
+	return r.existsIn(ctx, r.srcRef, target)
+}
+
+func (r *remoteManager) existsIn(ctx context.Context, ref string, target v1.Descriptor) (bool, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return false, err
+	}
+
+	digested, err := reference.WithDigest(reference.TrimNamed(named), target.Digest)
+	if err != nil {
+		return false, err
+	}
+
+	// resolving a digest reference issues HEAD requests against the manifests and blobs endpoints.
+	if _, _, err := r.resolver.Resolve(ctx, digested.String()); err != nil {
+		if errdefs.IsNotFound(err) {
+			return false, nil
+		}
+
 		return false, err
 	}
 
@@ -49,9 +75,9 @@ func (r *remoteManager) Push(ctx context.Context, expected v1.Descriptor, ctn io
 		return err
 	}
 
-	writer, err := pusher.Push(ctx, expected)
+	writer, err := pusher.Push(ctx, r.withMountSource(ctx, expected))
 	if err != nil {
-		if strings.Contains(err.Error(), "already exists") {
+		if errdefs.IsAlreadyExists(err) || strings.Contains(err.Error(), "already exists") {
 			return nil
 		}
 
@@ -72,6 +98,48 @@ func (r *remoteManager) Push(ctx context.Context, expected v1.Descriptor, ctn io
 	return writer.Commit(ctx, size, expected.Digest)
 }
 
+// withMountSource annotates a blob descriptor with the mount-from repositories that already hold the blob
+// in the same registry, so the pusher can attempt a cross-repository mount instead of uploading it.
+func (r *remoteManager) withMountSource(ctx context.Context, desc v1.Descriptor) v1.Descriptor {
+	if len(r.mountFrom) == 0 || images.IsManifestType(desc.MediaType) || images.IsIndexType(desc.MediaType) {
+		return desc
+	}
+
+	target, err := reference.ParseNormalizedNamed(r.srcRef)
+	if err != nil {
+		return desc
+	}
+
+	domain := reference.Domain(target)
+
+	var repos []string
+
+	for _, from := range r.mountFrom {
+		source, err := reference.ParseNormalizedNamed(from)
+		if err != nil || reference.Domain(source) != domain || reference.Path(source) == reference.Path(target) {
+			continue
+		}
+
+		if exists, err := r.existsIn(ctx, from, desc); err != nil || !exists {
+			continue
+		}
+
+		repos = append(repos, reference.Path(source))
+	}
+
+	if len(repos) == 0 {
+		return desc
+	}
+
+	annotations := make(map[string]string, len(desc.Annotations)+1)
+	maps.Copy(annotations, desc.Annotations)
+	annotations[labels.LabelDistributionSource+"."+domain] = strings.Join(repos, ",")
+
+	desc.Annotations = annotations
+
+	return desc
+}
+
 func (r *remoteManager) Tag(ctx context.Context, desc v1.Descriptor, reference string) error {
 	originalRef := r.srcRef
 
@@ -80,6 +148,8 @@ func (r *remoteManager) Tag(ctx context.Context, desc v1.Descriptor, reference s
 		return err
 	}
 
+	defer reader.Close()
+
 	r.srcRef = reference
 	desc.Annotations = make(map[string]string)
 	desc.Annotations[v1.AnnotationRefName] = reference
@@ -0,0 +1,145 @@
+package oci
+
+import (
+	"context"
+	"testing"
+
+	"github.com/containerd/containerd/v2/core/remotes"
+	"github.com/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver resolves the digest references of the blobs it holds, keyed by repository.
+type fakeResolver struct {
+	blobs    map[string][]digest.Digest
+	err      error
+	resolved []string
+}
+
+func (r *fakeResolver) Resolve(_ context.Context, ref string) (string, v1.Descriptor, error) {
+	r.resolved = append(r.resolved, ref)
+
+	if r.err != nil {
+		return "", v1.Descriptor{}, r.err
+	}
+
+	for repo, digests := range r.blobs {
+		for _, d := range digests {
+			if ref == repo+"@"+d.String() {
+				return ref, v1.Descriptor{Digest: d}, nil
+			}
+		}
+	}
+
+	return "", v1.Descriptor{}, errors.Wrapf(errdefs.ErrNotFound, "%s", ref)
+}
+
+func (r *fakeResolver) Fetcher(context.Context, string) (remotes.Fetcher, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
+func (r *fakeResolver) Pusher(context.Context, string) (remotes.Pusher, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
+var (
+	layerDigest = digest.FromString("layer")
+	layer       = v1.Descriptor{MediaType: v1.MediaTypeImageLayerGzip, Digest: layerDigest, Size: 5}
+)
+
+func TestExistsIn(t *testing.T) {
+	resolver := &fakeResolver{blobs: map[string][]digest.Digest{"ghcr.io/acme/base": {layerDigest}}}
+	r := &remoteManager{resolver: resolver}
+
+	exists, err := r.existsIn(context.Background(), "ghcr.io/acme/base:1.0", layer)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// the tag is replaced by the digest of the blob.
+	assert.Equal(t, []string{"ghcr.io/acme/base@" + layerDigest.String()}, resolver.resolved)
+
+	exists, err = r.existsIn(context.Background(), "ghcr.io/acme/other", layer)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	_, err = r.existsIn(context.Background(), "Invalid Ref", layer)
+	assert.Error(t, err)
+
+	resolver.err = errors.New("connection refused")
+	_, err = r.existsIn(context.Background(), "ghcr.io/acme/base", layer)
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+func TestWithMountSource(t *testing.T) {
+	resolver := &fakeResolver{blobs: map[string][]digest.Digest{
+		"ghcr.io/acme/base":   {layerDigest},
+		"ghcr.io/acme/shared": {layerDigest},
+		"docker.io/acme/base": {layerDigest},
+	}}
+
+	tcs := []struct {
+		name      string
+		mountFrom []string
+		desc      v1.Descriptor
+		expected  string
+	}{
+		{
+			name:      "repositories holding the blob",
+			mountFrom: []string{"ghcr.io/acme/base", "ghcr.io/acme/empty", "ghcr.io/acme/shared:1.0"},
+			desc:      layer,
+			expected:  "acme/base,acme/shared",
+		},
+		{
+			name:      "other registries",
+			mountFrom: []string{"docker.io/acme/base"},
+			desc:      layer,
+		},
+		{
+			name:      "target repository",
+			mountFrom: []string{"ghcr.io/acme/policy"},
+			desc:      layer,
+		},
+		{
+			name:      "manifests",
+			mountFrom: []string{"ghcr.io/acme/base"},
+			desc:      v1.Descriptor{MediaType: v1.MediaTypeImageManifest, Digest: layerDigest},
+		},
+		{
+			name: "no repositories",
+			desc: layer,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &remoteManager{resolver: resolver, srcRef: "ghcr.io/acme/policy:1.0", mountFrom: tc.mountFrom}
+
+			desc := r.withMountSource(context.Background(), tc.desc)
+
+			if tc.expected == "" {
+				assert.Empty(t, desc.Annotations)
+				return
+			}
+
+			assert.Equal(t, map[string]string{"containerd.io/distribution.source.ghcr.io": tc.expected}, desc.Annotations)
+		})
+	}
+}
+
+func TestWithMountSourceKeepsAnnotations(t *testing.T) {
+	resolver := &fakeResolver{blobs: map[string][]digest.Digest{"ghcr.io/acme/base": {layerDigest}}}
+	r := &remoteManager{resolver: resolver, srcRef: "ghcr.io/acme/policy", mountFrom: []string{"ghcr.io/acme/base"}}
+
+	desc := layer
+	desc.Annotations = map[string]string{v1.AnnotationTitle: "bundle.tar.gz"}
+
+	mounted := r.withMountSource(context.Background(), desc)
+
+	assert.Equal(t, "bundle.tar.gz", mounted.Annotations[v1.AnnotationTitle])
+	assert.Equal(t, "acme/base", mounted.Annotations["containerd.io/distribution.source.ghcr.io"])
+	assert.Len(t, desc.Annotations, 1, "the descriptor given is not modified")
+}
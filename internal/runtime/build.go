@@ -3,6 +3,7 @@ package runtime
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/bundle"
 	"github.com/open-policy-agent/opa/v1/compile"
+	"github.com/open-policy-agent/opa/v1/keys"
 	"github.com/open-policy-agent/opa/v1/loader"
 	"github.com/pkg/errors"
 )
@@ -102,29 +104,34 @@ func RegoVersionFromString(v string) RegoVersion {
 // BuildParams contains all parameters used for doing a build.
 type BuildParams struct {
 	CapabilitiesJSONFile string
-	Target               BuildTargetType
-	OptimizationLevel    int
-	Entrypoints          []string
-	OutputFile           string
-	Revision             string
-	Ignore               []string
-	Debug                bool
-	Algorithm            string
-	Key                  string
-	Scope                string
-	PubKey               string
-	PubKeyID             string
-	ClaimsFile           string
-	ExcludeVerifyFiles   []string
-	RegoVersion          RegoVersion
+	// Capabilities are used instead of those of CapabilitiesJSONFile when set, as for a capability profile.
+	Capabilities       *ast.Capabilities
+	Target             BuildTargetType
+	OptimizationLevel  int
+	Entrypoints        []string
+	OutputFile         string
+	Output             io.Writer // when set, the bundle is streamed here instead of to OutputFile.
+	Revision           string
+	Ignore             []string
+	Debug              bool
+	Algorithm          string
+	Key                string
+	Scope              string
+	PubKey             string
+	PubKeyID           string
+	ClaimsFile         string
+	ExcludeVerifyFiles []string
+	RegoVersion        RegoVersion
+	// Schemas type-check the modules, with the schemas declared in their METADATA annotations.
+	Schemas Schemas
 }
 
-// Build builds a policy bundle using OPA's compiler.
-func (r *Runtime) Build(params *BuildParams, paths []string) error {
-	buf := bytes.NewBuffer(nil)
-
+// Build builds a policy bundle using OPA's compiler and returns the warnings reported for its modules.
+//
+//nolint:funlen
+func (r *Runtime) Build(params *BuildParams, paths []string) ([]string, error) {
 	if err := generateAllStubBuiltins(paths); err != nil {
-		return err
+		return nil, err
 	}
 
 	// generate the bundle verification and signing config.
@@ -136,26 +143,32 @@ func (r *Runtime) Build(params *BuildParams, paths []string) error {
 	if params.PubKey != "" {
 		bvc, err = buildVerificationConfig(params.PubKey, params.PubKeyID, params.Algorithm, params.Scope, params.ExcludeVerifyFiles)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	bsc := buildSigningConfig(params.Key, params.Algorithm, params.ClaimsFile)
 
-	var capabilities *ast.Capabilities
-	// if capabilities are not provided then ast.CapabilitiesForThisVersion must be used.
-	if params.CapabilitiesJSONFile == "" {
-		capabilities = ast.CapabilitiesForThisVersion()
-	} else {
-		capabilitiesJSON, err := os.ReadFile(params.CapabilitiesJSONFile)
+	capabilities := params.Capabilities
+	if capabilities == nil {
+		capabilities, err = LoadCapabilities(params.CapabilitiesJSONFile)
 		if err != nil {
-			return errors.Wrapf(err, "couldn't read capabilities JSON file [%s]", params.CapabilitiesJSONFile)
+			return nil, err
 		}
+	}
 
-		capabilities, err = ast.LoadCapabilitiesJSON(bytes.NewBuffer(capabilitiesJSON))
+	var outFile *os.File
+
+	output := params.Output
+	if output == nil {
+		outFile, err = os.Create(params.OutputFile)
 		if err != nil {
-			return errors.Wrapf(err, "failed to load capabilities file [%s]", params.CapabilitiesJSONFile)
+			return nil, err
 		}
+
+		defer outFile.Close()
+
+		output = outFile
 	}
 
 	compiler := compile.New().
@@ -163,7 +176,6 @@ func (r *Runtime) Build(params *BuildParams, paths []string) error {
 		WithTarget(params.Target.String()).
 		WithAsBundle(true).
 		WithOptimizationLevel(params.OptimizationLevel).
-		WithOutput(buf).
 		WithEntrypoints(params.Entrypoints...).
 		WithPaths(paths...).
 		WithFilter(buildCommandLoaderFilter(true, params.Ignore)).
@@ -177,19 +189,76 @@ func (r *Runtime) Build(params *BuildParams, paths []string) error {
 	}
 
 	if err := compiler.Build(context.Background()); err != nil {
-		return err
+		return nil, undefinedFunctions(err, params, capabilities, paths)
+	}
+
+	// the OPA bundle compiler does not type-check against schemas, the modules are checked on their own.
+	if err := checkSchemas(params, capabilities, paths); err != nil {
+		return nil, err
+	}
+
+	// the compiler has no output, the bundle is written reproducibly instead of through the OPA bundle writer.
+	if err := writeBundle(output, compiler.Bundle()); err != nil {
+		return nil, errors.Wrap(err, "failed to write bundle")
+	}
+
+	warnings := bundleWarnings(compiler.Bundle())
+
+	if outFile != nil {
+		return warnings, outFile.Close()
+	}
+
+	return warnings, nil
+}
+
+// LoadCapabilities reads the capabilities of a JSON file, or returns the capabilities of this version of OPA
+// when no file is given.
+func LoadCapabilities(capabilitiesJSONFile string) (*ast.Capabilities, error) {
+	if capabilitiesJSONFile == "" {
+		return ast.CapabilitiesForThisVersion(), nil
 	}
 
-	out, err := os.Create(params.OutputFile)
+	capabilitiesJSON, err := os.ReadFile(capabilitiesJSONFile)
 	if err != nil {
-		return err
+		return nil, errors.Wrapf(err, "couldn't read capabilities JSON file [%s]", capabilitiesJSONFile)
 	}
 
-	if _, err := io.Copy(out, buf); err != nil {
-		return err
+	capabilities, err := ast.LoadCapabilitiesJSON(bytes.NewBuffer(capabilitiesJSON))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load capabilities file [%s]", capabilitiesJSONFile)
+	}
+
+	return capabilities, nil
+}
+
+// bundleWarnings reports the calls to deprecated built-in functions in the modules of a bundle.
+func bundleWarnings(b *bundle.Bundle) []string {
+	if b == nil {
+		return nil
+	}
+
+	var warnings []string
+
+	for _, mf := range b.Modules {
+		if mf.Parsed == nil {
+			continue
+		}
+
+		ast.WalkExprs(mf.Parsed, func(expr *ast.Expr) bool {
+			if !expr.IsCall() {
+				return false
+			}
+
+			name := expr.Operator().String()
+			if builtin, ok := ast.BuiltinMap[name]; ok && builtin.IsDeprecated() {
+				warnings = append(warnings, fmt.Sprintf("%s: use of deprecated built-in function %s", expr.Location, name))
+			}
+
+			return false
+		})
 	}
 
-	return out.Close()
+	return warnings
 }
 
 func buildCommandLoaderFilter(bundleMode bool, ignore []string) func(string, os.FileInfo, int) bool {
@@ -209,10 +278,10 @@ func buildVerificationConfig(pubKey, pubKeyID, alg, scope string, excludeFiles [
 		return nil, errors.New("pubKey is empty")
 	}
 
-	keyConfig := &bundle.KeyConfig{
-		Key:       pubKey,
-		Algorithm: alg,
-		Scope:     scope,
+	// the key is read from a file when one exists at its path.
+	keyConfig, err := keys.NewKeyConfig(pubKey, alg, scope)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the verification key")
 	}
 
 	return bundle.NewVerificationConfig(map[string]*bundle.KeyConfig{pubKeyID: keyConfig}, pubKeyID, scope, excludeFiles), nil
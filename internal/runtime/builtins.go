@@ -0,0 +1,373 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/loader"
+	"github.com/open-policy-agent/opa/v1/types"
+	"github.com/pkg/errors"
+)
+
+// UndefinedFunction is a function called by a policy that is neither a built-in nor defined by a rule.
+type UndefinedFunction struct {
+	Name      string   `json:"name"`
+	Arity     int      `json:"arity"`
+	Locations []string `json:"locations"`
+	// Rule is set when the call refers to a missing rule of a data document, which cannot be stubbed.
+	Rule bool `json:"rule,omitempty"`
+}
+
+// ScanFunctions parses the modules below paths, skipping the ignored files, and returns the functions they call
+// that are neither built-ins of capabilities nor defined by any of the rules, sorted by name.
+func ScanFunctions(paths, ignore []string, capabilities *ast.Capabilities, regoVersion RegoVersion) ([]UndefinedFunction, error) {
+	if capabilities == nil {
+		capabilities = ast.CapabilitiesForThisVersion()
+	}
+
+	result, err := loader.NewFileLoader().
+		WithRegoVersion(regoVersion.ToAstRegoVersion()).
+		WithCapabilities(capabilities).
+		Filtered(paths, buildCommandLoaderFilter(false, ignore))
+	if err != nil {
+		return nil, err
+	}
+
+	builtins := map[string]bool{}
+	for _, builtin := range capabilities.Builtins {
+		builtins[builtin.Name] = true
+	}
+
+	// rules are the paths of the rules, packages the names of the rules of each package, defined in any module.
+	rules := map[string]bool{}
+	packages := map[string]map[string]bool{}
+
+	for _, file := range result.Modules {
+		pkg := file.Parsed.Package.Path.String()
+		if packages[pkg] == nil {
+			packages[pkg] = map[string]bool{}
+		}
+
+		for _, rule := range file.Parsed.Rules {
+			rules[rule.Path().String()] = true
+			packages[pkg][ruleName(rule)] = true
+		}
+	}
+
+	// the arity of the functions declared in the manifests settles calls with different arities.
+	declared := map[string]int{}
+
+	for _, path := range paths {
+		defs, err := readManifestBuiltins(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if defs != nil {
+			for _, builtin := range defs.All() {
+				declared[builtin.Name] = len(builtin.Decl.FuncArgs().Args)
+			}
+		}
+	}
+
+	found := map[string]*UndefinedFunction{}
+
+	for _, file := range result.ParsedModules() {
+		var conflict error
+
+		scanCalls(file, func(op ast.Ref, arity int, loc *ast.Location) {
+			name, isRule := resolveCall(file, packages, op)
+			if builtins[op.String()] || rules[name] {
+				return
+			}
+
+			location := ""
+			if loc != nil {
+				location = fmt.Sprintf("%s:%d", loc.File, loc.Row)
+			}
+
+			fn, ok := found[name]
+			if !ok {
+				fn = &UndefinedFunction{Name: name, Arity: arity, Rule: isRule}
+				found[name] = fn
+			}
+
+			declaredArity, isDeclared := declared[name]
+			if isDeclared {
+				fn.Arity = declaredArity
+			}
+
+			// a call used as a statement may pass an extra output argument, the arity cannot be told from the calls.
+			if !isDeclared && fn.Arity != arity && conflict == nil {
+				conflict = errors.Errorf("function [%s] is called with arity %d at %s and %d at %s, "+
+					"declare it as a required builtin in the .manifest", name, fn.Arity, strings.Join(fn.Locations, ", "),
+					arity, location)
+			}
+
+			if location != "" {
+				fn.Locations = append(fn.Locations, location)
+			}
+		})
+
+		if conflict != nil {
+			return nil, conflict
+		}
+	}
+
+	functions := make([]UndefinedFunction, 0, len(found))
+	for _, fn := range found {
+		sort.Strings(fn.Locations)
+		functions = append(functions, *fn)
+	}
+
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].Name < functions[j].Name
+	})
+
+	return functions, nil
+}
+
+// scanCalls reports every function call of a module, as a statement or as a term.
+func scanCalls(module *ast.Module, f func(op ast.Ref, arity int, loc *ast.Location)) {
+	ast.NewGenericVisitor(func(x any) bool {
+		switch x := x.(type) {
+		case *ast.Expr:
+			if x.IsCall() {
+				f(x.Operator(), len(x.Operands()), x.Location)
+			}
+		case ast.Call:
+			if op, ok := x[0].Value.(ast.Ref); ok {
+				f(op, len(x)-1, x[0].Location)
+			}
+		}
+
+		return false
+	}).Walk(module)
+}
+
+// resolveCall returns the name a call resolves to, the full path of a rule for calls into data through the data root,
+// an import or a rule of the package of the module, defined in any of its modules, or the called name for functions
+// expected to be built-ins.
+func resolveCall(module *ast.Module, packages map[string]map[string]bool, op ast.Ref) (string, bool) {
+	head, ok := op[0].Value.(ast.Var)
+	if !ok {
+		return op.String(), false
+	}
+
+	if head.Equal(ast.DefaultRootDocument.Value) {
+		return op.String(), true
+	}
+
+	for _, imp := range module.Imports {
+		path, ok := imp.Path.Value.(ast.Ref)
+		if ok && imp.Name().Equal(head) && path.HasPrefix(ast.DefaultRootRef) {
+			return path.Concat(op[1:]).String(), true
+		}
+	}
+
+	if packages[module.Package.Path.String()][string(head)] {
+		return module.Package.Path.Append(ast.StringTerm(string(head))).Concat(op[1:]).String(), true
+	}
+
+	return op.String(), false
+}
+
+// ruleName returns the first segment of the name of a rule.
+func ruleName(rule *ast.Rule) string {
+	if v, ok := rule.Head.Ref()[0].Value.(ast.Var); ok {
+		return string(v)
+	}
+
+	return rule.Head.Ref()[0].String()
+}
+
+// UndefinedFunctionsError reports the functions of a policy that could not be resolved by the compiler.
+type UndefinedFunctionsError struct {
+	Functions []UndefinedFunction
+	Err       error
+}
+
+func (e *UndefinedFunctionsError) Error() string {
+	var sb strings.Builder
+
+	sb.WriteString("undefined functions, define them in a rule or declare them as required builtins " +
+		"in the .manifest with 'policy builtins scan':")
+
+	for _, fn := range e.Functions {
+		fmt.Fprintf(&sb, "\n  %s/%d at %s", fn.Name, fn.Arity, strings.Join(fn.Locations, ", "))
+	}
+
+	if e.Err != nil {
+		sb.WriteString("\n")
+		sb.WriteString(e.Err.Error())
+	}
+
+	return sb.String()
+}
+
+func (e *UndefinedFunctionsError) Unwrap() error {
+	return e.Err
+}
+
+// undefinedFunctions explains a failed build by the functions that are neither built-ins, stubs declared in
+// the manifests nor rules. It returns the original error when it is not a compile error, there are no such functions
+// or the sources cannot be scanned.
+func undefinedFunctions(buildErr error, params *BuildParams, capabilities *ast.Capabilities, paths []string) error {
+	var astErrs ast.Errors
+	if !errors.As(buildErr, &astErrs) {
+		return buildErr
+	}
+
+	functions, err := ScanFunctions(paths, params.Ignore, capabilities, params.RegoVersion)
+	if err != nil {
+		return buildErr
+	}
+
+	declared := map[string]bool{}
+
+	for _, path := range paths {
+		defs, err := readManifestBuiltins(path)
+		if err != nil || defs == nil {
+			continue
+		}
+
+		for _, builtin := range defs.All() {
+			declared[builtin.Name] = true
+		}
+	}
+
+	functions = slices.DeleteFunc(functions, func(fn UndefinedFunction) bool {
+		return declared[fn.Name]
+	})
+
+	if len(functions) == 0 {
+		return buildErr
+	}
+
+	return &UndefinedFunctionsError{Functions: functions, Err: buildErr}
+}
+
+// UpdateManifestBuiltins declares the functions that can be stubbed in the required builtins of a manifest,
+// creating it when needed. Existing declarations with the same arity are kept, other manifest fields are preserved.
+func UpdateManifestBuiltins(manifestPath string, functions []UndefinedFunction) (*StubBuiltinDefs, error) {
+	manifest := map[string]any{}
+
+	buf, err := os.ReadFile(manifestPath)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(buf, &manifest); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal json from manifest [%s]", manifestPath)
+		}
+	case !os.IsNotExist(err):
+		return nil, errors.Wrapf(err, "failed to read manifest [%s]", manifestPath)
+	}
+
+	existing := map[string]StubBuiltin{}
+
+	if current, err := readManifestBuiltinsFile(manifestPath); err == nil && current != nil {
+		for _, builtin := range current.All() {
+			existing[builtin.Name] = builtin
+		}
+	}
+
+	for _, fn := range functions {
+		if fn.Rule {
+			continue
+		}
+
+		if builtin, ok := existing[fn.Name]; ok && len(builtin.Decl.FuncArgs().Args) == fn.Arity {
+			continue
+		}
+
+		args := make([]types.Type, fn.Arity)
+		for i := range args {
+			args[i] = types.A
+		}
+
+		existing[fn.Name] = StubBuiltin{Name: fn.Name, Decl: *types.NewFunction(args, types.A)}
+	}
+
+	defs := &StubBuiltinDefs{}
+
+	names := make([]string, 0, len(existing))
+	for name := range existing {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		defs.add(existing[name])
+	}
+
+	metadata, _ := manifest["metadata"].(map[string]any)
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	metadata["required_builtins"] = defs
+	manifest["metadata"] = metadata
+
+	out := &bytes.Buffer{}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(manifest); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(manifestPath, out.Bytes(), 0o600); err != nil {
+		return nil, errors.Wrapf(err, "failed to write manifest [%s]", manifestPath)
+	}
+
+	return defs, nil
+}
+
+func (d *StubBuiltinDefs) add(builtin StubBuiltin) {
+	switch len(builtin.Decl.FuncArgs().Args) {
+	case 1:
+		d.Builtin1 = append(d.Builtin1, StubBuiltin1(builtin))
+	case 2:
+		d.Builtin2 = append(d.Builtin2, StubBuiltin2(builtin))
+	case 3:
+		d.Builtin3 = append(d.Builtin3, StubBuiltin3(builtin))
+	case 4:
+		d.Builtin4 = append(d.Builtin4, StubBuiltin4(builtin))
+	default:
+		d.BuiltinDyn = append(d.BuiltinDyn, StubBuiltinDyn(builtin))
+	}
+}
+
+// All returns the builtins of every arity.
+func (d *StubBuiltinDefs) All() []StubBuiltin {
+	var builtins []StubBuiltin
+
+	for _, b := range d.Builtin1 {
+		builtins = append(builtins, StubBuiltin(b))
+	}
+
+	for _, b := range d.Builtin2 {
+		builtins = append(builtins, StubBuiltin(b))
+	}
+
+	for _, b := range d.Builtin3 {
+		builtins = append(builtins, StubBuiltin(b))
+	}
+
+	for _, b := range d.Builtin4 {
+		builtins = append(builtins, StubBuiltin(b))
+	}
+
+	for _, b := range d.BuiltinDyn {
+		builtins = append(builtins, StubBuiltin(b))
+	}
+
+	return builtins
+}
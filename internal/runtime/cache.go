@@ -0,0 +1,179 @@
+package runtime
+
+import (
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/version"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// buildKeyVersion changes whenever the bundle written for the same inputs changes.
+const buildKeyVersion = "1"
+
+type buildKeyInput struct {
+	Version            string
+	OPAVersion         string
+	Files              map[string]digest.Digest
+	Capabilities       digest.Digest
+	Target             string
+	RegoVersion        string
+	OptimizationLevel  int
+	Entrypoints        []string
+	Revision           string
+	Algorithm          string
+	Key                digest.Digest
+	Scope              string
+	PubKey             digest.Digest
+	PubKeyID           string
+	ClaimsFile         digest.Digest
+	ExcludeVerifyFiles []string
+	Schemas            digest.Digest
+}
+
+// BuildKey returns a digest identifying the bundle built from paths with params.
+// It covers the content of every input file left after applying the ignore patterns,
+// and every parameter that changes the compiled bundle.
+func BuildKey(params *BuildParams, paths []string) (digest.Digest, error) {
+	files, err := inputFiles(paths, buildCommandLoaderFilter(true, params.Ignore))
+	if err != nil {
+		return "", err
+	}
+
+	input := buildKeyInput{
+		Version:            buildKeyVersion,
+		OPAVersion:         version.Version,
+		Files:              files,
+		Target:             params.Target.String(),
+		RegoVersion:        params.RegoVersion.String(),
+		OptimizationLevel:  params.OptimizationLevel,
+		Entrypoints:        params.Entrypoints,
+		Revision:           params.Revision,
+		Algorithm:          params.Algorithm,
+		Scope:              params.Scope,
+		PubKeyID:           params.PubKeyID,
+		ExcludeVerifyFiles: params.ExcludeVerifyFiles,
+	}
+
+	if len(params.Schemas) > 0 {
+		buf, err := json.Marshal(params.Schemas)
+		if err != nil {
+			return "", err
+		}
+
+		input.Schemas = digest.FromBytes(buf)
+	}
+
+	// keys are given either inline or as a file path, the key input covers both.
+	for _, v := range []struct {
+		value  string
+		target *digest.Digest
+	}{
+		{params.CapabilitiesJSONFile, &input.Capabilities},
+		{params.Key, &input.Key},
+		{params.PubKey, &input.PubKey},
+		{params.ClaimsFile, &input.ClaimsFile},
+	} {
+		if *v.target, err = valueDigest(v.value); err != nil {
+			return "", err
+		}
+	}
+
+	// capabilities given as such, those of a profile, replace the capabilities file.
+	if params.Capabilities != nil {
+		buf, err := json.Marshal(params.Capabilities)
+		if err != nil {
+			return "", err
+		}
+
+		input.Capabilities = digest.FromBytes(buf)
+	}
+
+	buf, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+
+	return digest.FromBytes(buf), nil
+}
+
+// inputFiles returns the digest of every file below paths that is not excluded by filter.
+func inputFiles(paths []string, filter func(string, os.FileInfo, int) bool) (map[string]digest.Digest, error) {
+	files := map[string]digest.Digest{}
+
+	for _, root := range paths {
+		err := filepath.Walk(root, func(path string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if filter(path, info, depth(root, path)) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+
+			fileDigest, err := fileDigest(path)
+			if err != nil {
+				return err
+			}
+
+			files[path] = fileDigest
+
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read build input [%s]", root)
+		}
+	}
+
+	return files, nil
+}
+
+func depth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+
+	return strings.Count(filepath.ToSlash(rel), "/") + 1
+}
+
+func valueDigest(value string) (digest.Digest, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	exists, err := fileExists(value)
+	if err != nil || !exists {
+		return digest.FromString(value), nil //nolint:nilerr // not a file, the value itself is the input.
+	}
+
+	return fileDigest(value)
+}
+
+func fileDigest(path string) (digest.Digest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	digester := digest.Canonical.Digester()
+	if _, err := io.Copy(digester.Hash(), f); err != nil {
+		return "", err
+	}
+
+	return digester.Digest(), nil
+}
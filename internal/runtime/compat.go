@@ -0,0 +1,206 @@
+package runtime
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/open-policy-agent/opa/v1/loader"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/semver"
+)
+
+// SourceModule is the source of a module checked against the capabilities of OPA versions.
+type SourceModule struct {
+	Path        string
+	Raw         []byte
+	RegoVersion ast.RegoVersion
+}
+
+// VersionCompatibility tells whether a version of OPA can load a policy, and why it cannot.
+type VersionCompatibility struct {
+	// Requested is the version as given, Version the released version whose capabilities were used.
+	Requested  string   `json:"requested"`
+	Version    string   `json:"version"`
+	Compatible bool     `json:"compatible"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// ResolveOPAVersion returns the release of OPA with embedded capabilities for a version given as '1.4', 'v1.4'
+// or '1.4.2'. A version without patch level resolves to its first patch release.
+func ResolveOPAVersion(version string) (string, error) {
+	versions, err := ast.LoadCapabilitiesVersions()
+	if err != nil {
+		return "", err
+	}
+
+	wanted := "v" + strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if !semver.IsValid(wanted) {
+		return "", errors.Errorf("invalid OPA version [%s]", version)
+	}
+
+	for _, v := range versions {
+		if v == wanted || (semver.Canonical(wanted) != wanted && semver.MajorMinor(v) == wanted) {
+			return v, nil
+		}
+	}
+
+	return "", errors.Errorf("no capabilities for OPA version [%s], versions %s to %s are known",
+		version, versions[0], versions[len(versions)-1])
+}
+
+// LoadSourceModules reads the modules below paths, skipping the ignored files, and the required builtins declared
+// in their manifests.
+func LoadSourceModules(paths, ignore []string, regoVersion RegoVersion) ([]SourceModule, []StubBuiltin, error) {
+	result, err := loader.NewFileLoader().
+		WithRegoVersion(regoVersion.ToAstRegoVersion()).
+		Filtered(paths, buildCommandLoaderFilter(true, ignore))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	modules := make([]SourceModule, 0, len(result.Modules))
+	for _, m := range result.Modules {
+		modules = append(modules, SourceModule{Path: m.Name, Raw: m.Raw, RegoVersion: regoVersion.ToAstRegoVersion()})
+	}
+
+	stubs := []StubBuiltin{}
+
+	for _, path := range paths {
+		defs, err := readManifestBuiltins(path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if defs != nil {
+			stubs = append(stubs, defs.All()...)
+		}
+	}
+
+	return modules, stubs, nil
+}
+
+// BundleModules returns the modules of a bundle, in the rego version the bundle records for each of them,
+// and the required builtins declared in its manifest.
+func BundleModules(b *bundle.Bundle, regoVersion ast.RegoVersion) ([]SourceModule, []StubBuiltin, error) {
+	modules := make([]SourceModule, 0, len(b.Modules))
+
+	for _, m := range b.Modules {
+		version, err := b.RegoVersionForFile(m.Path, regoVersion)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		modules = append(modules, SourceModule{Path: m.Path, Raw: m.Raw, RegoVersion: version})
+	}
+
+	defs, err := ManifestBuiltins(&b.Manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stubs := []StubBuiltin{}
+	if defs != nil {
+		stubs = defs.All()
+	}
+
+	return modules, stubs, nil
+}
+
+// ManifestBuiltins returns the required builtins declared in the metadata of a bundle manifest, nil when there
+// are none.
+func ManifestBuiltins(m *bundle.Manifest) (*StubBuiltinDefs, error) {
+	buf, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := MetadataEx{}
+	if err := json.Unmarshal(buf, &manifest); err != nil {
+		return nil, err
+	}
+
+	return manifest.Metadata.RequiredBuiltins, nil
+}
+
+// CheckCompatibility parses and compiles the modules with the capabilities of an OPA release, as the version would
+// when loading the bundle.
+func CheckCompatibility(modules []SourceModule, stubs []StubBuiltin, version string) (*VersionCompatibility, error) {
+	capabilities, err := ast.LoadCapabilitiesVersion(version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load capabilities of OPA %s", version)
+	}
+
+	errs, err := CheckCapabilities(modules, stubs, capabilities)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VersionCompatibility{Version: version, Compatible: len(errs) == 0, Errors: BriefErrors(errs)}, nil
+}
+
+// CheckCapabilities parses and compiles the modules with capabilities and returns the errors, none when the modules
+// only use what the capabilities provide. The required builtins are provided by the runtime the policy is deployed
+// to, they are added to the capabilities.
+func CheckCapabilities(modules []SourceModule, stubs []StubBuiltin, capabilities *ast.Capabilities) (ast.Errors, error) {
+	provided := *capabilities
+	provided.Builtins = slices.Clone(capabilities.Builtins)
+
+	for _, stub := range stubs {
+		if !slices.ContainsFunc(provided.Builtins, func(b *ast.Builtin) bool { return b.Name == stub.Name }) {
+			decl := stub.Decl
+			provided.Builtins = append(provided.Builtins, &ast.Builtin{Name: stub.Name, Decl: &decl})
+		}
+	}
+
+	parsed := map[string]*ast.Module{}
+	errs := ast.Errors{}
+
+	for _, m := range modules {
+		module, err := ast.ParseModuleWithOpts(m.Path, string(m.Raw), ast.ParserOptions{
+			Capabilities:      &provided,
+			RegoVersion:       m.RegoVersion,
+			ProcessAnnotation: true,
+		})
+		if err != nil {
+			var parseErrs ast.Errors
+			if !errors.As(err, &parseErrs) {
+				return nil, err
+			}
+
+			errs = append(errs, parseErrs...)
+
+			continue
+		}
+
+		parsed[m.Path] = module
+	}
+
+	if len(errs) == 0 {
+		compiler := ast.NewCompiler().WithCapabilities(&provided)
+		if compiler.Compile(parsed); compiler.Failed() {
+			errs = compiler.Errors
+		}
+	}
+
+	return errs, nil
+}
+
+// BriefErrors returns the messages of errors without the source excerpt of their details, those without location,
+// as for missing features, once.
+func BriefErrors(errs ast.Errors) []string {
+	messages := []string{}
+
+	for _, err := range errs {
+		brief := *err
+		brief.Details = nil
+
+		if msg := brief.Error(); !slices.Contains(messages, msg) {
+			messages = append(messages, msg)
+		}
+	}
+
+	return messages
+}
@@ -0,0 +1,268 @@
+package runtime
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/cover"
+	"github.com/pkg/errors"
+)
+
+// Coverage report formats.
+const (
+	CoverageJSON      = "json"
+	CoverageLCOV      = "lcov"
+	CoverageCobertura = "cobertura"
+)
+
+// sourceCoverage returns the coverage of the modules, the tests left out, in the JSON format of OPA.
+func sourceCoverage(tracer *cover.Cover, modules map[string]*ast.Module) *cover.Report {
+	sources := map[string]*ast.Module{}
+
+	for file, module := range modules {
+		if !isTestFile(file) {
+			sources[file] = module
+		}
+	}
+
+	report := tracer.Report(sources)
+
+	// the tracer also reports the hits of the tests themselves.
+	report.CoveredLines = 0
+	report.NotCoveredLines = 0
+	report.Coverage = 0
+
+	for file, fr := range report.Files {
+		if _, ok := sources[file]; !ok {
+			delete(report.Files, file)
+			continue
+		}
+
+		report.CoveredLines += fr.CoveredLines
+		report.NotCoveredLines += fr.NotCoveredLines
+	}
+
+	if total := report.CoveredLines + report.NotCoveredLines; total > 0 {
+		report.Coverage = 100 * float64(report.CoveredLines) / float64(total)
+	}
+
+	return &report
+}
+
+// WriteCoverage writes a coverage report as OPA JSON, lcov or Cobertura XML, Cobertura reports record timestamp as
+// the time of the run.
+func WriteCoverage(w io.Writer, report *cover.Report, format string, timestamp time.Time) error {
+	switch format {
+	case CoverageJSON, "":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(report)
+	case CoverageLCOV:
+		return writeLCOV(w, report)
+	case CoverageCobertura:
+		return writeCobertura(w, report, timestamp)
+	default:
+		return errors.Errorf("unknown coverage format [%s], use json, lcov or cobertura", format)
+	}
+}
+
+func writeLCOV(w io.Writer, report *cover.Report) error {
+	for _, file := range coveredFiles(report) {
+		lines := fileLines(report.Files[file])
+
+		if _, err := fmt.Fprintf(w, "TN:\nSF:%s\n", file); err != nil {
+			return err
+		}
+
+		hit := 0
+
+		for _, line := range lines {
+			if line.hits > 0 {
+				hit++
+			}
+
+			if _, err := fmt.Fprintf(w, "DA:%d,%d\n", line.number, line.hits); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "LF:%d\nLH:%d\nend_of_record\n", len(lines), hit); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type coberturaCoverage struct {
+	XMLName         xml.Name           `xml:"coverage"`
+	LineRate        string             `xml:"line-rate,attr"`
+	BranchRate      string             `xml:"branch-rate,attr"`
+	LinesCovered    int                `xml:"lines-covered,attr"`
+	LinesValid      int                `xml:"lines-valid,attr"`
+	BranchesCovered int                `xml:"branches-covered,attr"`
+	BranchesValid   int                `xml:"branches-valid,attr"`
+	Complexity      int                `xml:"complexity,attr"`
+	Version         string             `xml:"version,attr"`
+	Timestamp       int64              `xml:"timestamp,attr"`
+	Sources         []string           `xml:"sources>source"`
+	Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   string           `xml:"line-rate,attr"`
+	BranchRate string           `xml:"branch-rate,attr"`
+	Complexity int              `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Name       string          `xml:"name,attr"`
+	Filename   string          `xml:"filename,attr"`
+	LineRate   string          `xml:"line-rate,attr"`
+	BranchRate string          `xml:"branch-rate,attr"`
+	Complexity int             `xml:"complexity,attr"`
+	Methods    struct{}        `xml:"methods"`
+	Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number int `xml:"number,attr"`
+	Hits   int `xml:"hits,attr"`
+}
+
+// writeCobertura writes the report in the Cobertura XML format, the directories of the modules are its packages
+// and the modules its classes.
+func writeCobertura(w io.Writer, report *cover.Report, timestamp time.Time) error {
+	doc := coberturaCoverage{
+		LineRate:     lineRate(report.CoveredLines, report.NotCoveredLines),
+		BranchRate:   "0",
+		LinesCovered: report.CoveredLines,
+		LinesValid:   report.CoveredLines + report.NotCoveredLines,
+		Timestamp:    timestamp.UnixMilli(),
+		Sources:      []string{"."},
+	}
+
+	packages := map[string]*coberturaPackage{}
+	names := []string{}
+
+	for _, file := range coveredFiles(report) {
+		fr := report.Files[file]
+		dir := path.Dir(file)
+
+		pkg, ok := packages[dir]
+		if !ok {
+			pkg = &coberturaPackage{Name: dir, BranchRate: "0"}
+			packages[dir] = pkg
+			names = append(names, dir)
+		}
+
+		class := coberturaClass{
+			Name:       path.Base(file),
+			Filename:   file,
+			LineRate:   lineRate(fr.CoveredLines, fr.NotCoveredLines),
+			BranchRate: "0",
+		}
+
+		for _, line := range fileLines(fr) {
+			class.Lines = append(class.Lines, coberturaLine{Number: line.number, Hits: line.hits})
+		}
+
+		pkg.Classes = append(pkg.Classes, class)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		pkg := packages[name]
+
+		covered, valid := 0, 0
+		for _, class := range pkg.Classes {
+			for _, line := range class.Lines {
+				valid++
+				if line.Hits > 0 {
+					covered++
+				}
+			}
+		}
+
+		pkg.LineRate = lineRate(covered, valid-covered)
+		doc.Packages = append(doc.Packages, *pkg)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
+
+type coverageLine struct {
+	number int
+	hits   int
+}
+
+// fileLines returns the lines of a file report with a hit for each covered one, OPA does not count the hits.
+func fileLines(fr *cover.FileReport) []coverageLine {
+	hits := map[int]int{}
+
+	for _, r := range fr.NotCovered {
+		for row := r.Start.Row; row <= r.End.Row; row++ {
+			hits[row] = 0
+		}
+	}
+
+	for _, r := range fr.Covered {
+		for row := r.Start.Row; row <= r.End.Row; row++ {
+			hits[row] = 1
+		}
+	}
+
+	lines := make([]coverageLine, 0, len(hits))
+	for number, hit := range hits {
+		lines = append(lines, coverageLine{number: number, hits: hit})
+	}
+
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i].number < lines[j].number
+	})
+
+	return lines
+}
+
+func coveredFiles(report *cover.Report) []string {
+	files := make([]string, 0, len(report.Files))
+	for file := range report.Files {
+		files = append(files, file)
+	}
+
+	sort.Strings(files)
+
+	return files
+}
+
+func lineRate(covered, notCovered int) string {
+	if covered+notCovered == 0 {
+		return "1"
+	}
+
+	return strconv.FormatFloat(float64(covered)/float64(covered+notCovered), 'f', 4, 64)
+}
@@ -0,0 +1,431 @@
+package runtime
+
+import (
+	"encoding/json"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/loader"
+	"github.com/pkg/errors"
+)
+
+// PackageDoc documents a package from the METADATA annotations of its modules.
+type PackageDoc struct {
+	Path             string         `json:"path"`
+	Title            string         `json:"title,omitempty"`
+	Description      string         `json:"description,omitempty"`
+	Authors          []string       `json:"authors,omitempty"`
+	Organizations    []string       `json:"organizations,omitempty"`
+	RelatedResources []string       `json:"related_resources,omitempty"`
+	Custom           map[string]any `json:"custom,omitempty"`
+	Rules            []RuleDoc      `json:"rules"`
+}
+
+// RuleDoc documents a rule, or a document made of several rules, of a package.
+type RuleDoc struct {
+	Path        string         `json:"path"`
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Entrypoint  bool           `json:"entrypoint,omitempty"`
+	Authors     []string       `json:"authors,omitempty"`
+	Custom      map[string]any `json:"custom,omitempty"`
+	Schemas     []SchemaDoc    `json:"schemas,omitempty"`
+	Location    string         `json:"location"`
+}
+
+// SchemaDoc is a schema declared for a rule, as a reference to a schema document or an inline definition.
+type SchemaDoc struct {
+	Path       string `json:"path"`
+	Schema     string `json:"schema,omitempty"`
+	Definition string `json:"definition,omitempty"`
+}
+
+// PackageSummary is the short form of a package documentation recorded on images.
+type PackageSummary struct {
+	Package     string   `json:"package"`
+	Title       string   `json:"title,omitempty"`
+	Entrypoints []string `json:"entrypoints,omitempty"`
+}
+
+// LoadAnnotatedModules parses the modules below paths, skipping the ignored files, with their METADATA annotations.
+func LoadAnnotatedModules(paths, ignore []string, regoVersion RegoVersion) ([]*ast.Module, error) {
+	result, err := loader.NewFileLoader().
+		WithRegoVersion(regoVersion.ToAstRegoVersion()).
+		WithProcessAnnotation(true).
+		Filtered(paths, buildCommandLoaderFilter(false, ignore))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(result.Modules))
+	for file := range result.Modules {
+		files = append(files, file)
+	}
+
+	sort.Strings(files)
+
+	modules := make([]*ast.Module, 0, len(files))
+	for _, file := range files {
+		modules = append(modules, result.Modules[file].Parsed)
+	}
+
+	return modules, nil
+}
+
+func isTestFile(file string) bool {
+	return strings.HasSuffix(file, "_test.rego")
+}
+
+// Document collects the documentation of the packages of modules, sorted by path. Every package and rule is listed,
+// annotated or not, test files are left out. Package annotations apply to the package they are written on only.
+func Document(modules []*ast.Module) ([]PackageDoc, error) {
+	as, errs := ast.BuildAnnotationSet(modules)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	packages := map[string]*PackageDoc{}
+	rules := map[string]*RuleDoc{}
+
+	for _, module := range modules {
+		if module.Package.Location != nil && isTestFile(module.Package.Location.File) {
+			continue
+		}
+
+		pkgPath := module.Package.Path.String()
+
+		pkg, ok := packages[pkgPath]
+		if !ok {
+			pkg = &PackageDoc{Path: pkgPath, Rules: []RuleDoc{}}
+			packages[pkgPath] = pkg
+		}
+
+		if a := as.GetPackageScope(module.Package); a != nil {
+			pkg.annotate(a)
+		}
+
+		for _, rule := range module.Rules {
+			// rules with variables in their reference, as 'p[x]', document the ground prefix of the reference.
+			name := rule.Path().String()
+
+			doc, ok := rules[name]
+			if !ok {
+				doc = &RuleDoc{Path: name, Location: location(rule.Location)}
+				rules[name] = doc
+
+				if a := as.GetDocumentScope(rule.Ref().GroundPrefix()); a != nil {
+					doc.annotate(a)
+				}
+			}
+
+			for _, a := range as.GetRuleScope(rule) {
+				doc.annotate(a)
+			}
+		}
+	}
+
+	for name, rule := range rules {
+		pkg := packages[packageOf(name, packages)]
+		pkg.Rules = append(pkg.Rules, *rule)
+	}
+
+	docs := make([]PackageDoc, 0, len(packages))
+
+	for _, pkg := range packages {
+		sort.Slice(pkg.Rules, func(i, j int) bool {
+			return pkg.Rules[i].Path < pkg.Rules[j].Path
+		})
+
+		docs = append(docs, *pkg)
+	}
+
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].Path < docs[j].Path
+	})
+
+	return docs, nil
+}
+
+// packageOf returns the longest package path prefixing a rule path.
+func packageOf(rule string, packages map[string]*PackageDoc) string {
+	found := ""
+
+	for pkg := range packages {
+		if strings.HasPrefix(rule, pkg+".") && len(pkg) > len(found) {
+			found = pkg
+		}
+	}
+
+	return found
+}
+
+func (p *PackageDoc) annotate(a *ast.Annotations) {
+	p.Title = first(p.Title, a.Title)
+	p.Description = first(p.Description, a.Description)
+	p.Authors = append(p.Authors, authors(a)...)
+	p.Organizations = append(p.Organizations, a.Organizations...)
+	p.Custom = mergeCustom(p.Custom, a.Custom)
+
+	for _, r := range a.RelatedResources {
+		resource := r.Ref.String()
+		if r.Description != "" {
+			resource += " (" + r.Description + ")"
+		}
+
+		p.RelatedResources = append(p.RelatedResources, resource)
+	}
+}
+
+func (r *RuleDoc) annotate(a *ast.Annotations) {
+	r.Title = first(r.Title, a.Title)
+	r.Description = first(r.Description, a.Description)
+	r.Entrypoint = r.Entrypoint || a.Entrypoint
+	r.Authors = append(r.Authors, authors(a)...)
+	r.Custom = mergeCustom(r.Custom, a.Custom)
+
+	for _, s := range a.Schemas {
+		schema := SchemaDoc{Path: s.Path.String()}
+
+		switch {
+		case s.Schema != nil:
+			schema.Schema = s.Schema.String()
+		case s.Definition != nil:
+			buf, err := json.MarshalIndent(*s.Definition, "", "  ")
+			if err == nil {
+				schema.Definition = string(buf)
+			}
+		}
+
+		r.Schemas = append(r.Schemas, schema)
+	}
+}
+
+func authors(a *ast.Annotations) []string {
+	names := make([]string, 0, len(a.Authors))
+	for _, author := range a.Authors {
+		names = append(names, author.String())
+	}
+
+	return names
+}
+
+func first(current, next string) string {
+	if current != "" {
+		return current
+	}
+
+	return next
+}
+
+// mergeCustom adds the custom annotations of a rule definition, the first definition wins.
+func mergeCustom(current, next map[string]any) map[string]any {
+	if len(next) == 0 {
+		return current
+	}
+
+	if current == nil {
+		current = map[string]any{}
+	}
+
+	for k, v := range next {
+		if _, ok := current[k]; !ok {
+			current[k] = v
+		}
+	}
+
+	return current
+}
+
+// Summarize returns the summary of the documented packages recorded on images.
+func Summarize(docs []PackageDoc) []PackageSummary {
+	summaries := make([]PackageSummary, 0, len(docs))
+
+	for _, pkg := range docs {
+		summary := PackageSummary{Package: pkg.Path, Title: pkg.Title}
+
+		for _, rule := range pkg.Rules {
+			if rule.Entrypoint {
+				summary.Entrypoints = append(summary.Entrypoints, rule.Path)
+			}
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries
+}
+
+var docFuncs = map[string]any{
+	"custom": func(v any) string {
+		if s, ok := v.(string); ok {
+			return s
+		}
+
+		buf, _ := json.Marshal(v)
+
+		return string(buf)
+	},
+	"keys": func(m map[string]any) []string {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		return keys
+	},
+}
+
+var markdownDoc = template.Must(template.New("markdown").Funcs(docFuncs).Parse(
+	`{{- range $i, $pkg := . }}{{ if $i }}
+{{ end }}# {{ if $pkg.Title }}{{ $pkg.Title }}{{ else }}{{ $pkg.Path }}{{ end }}
+
+Package: ` + "`{{ $pkg.Path }}`" + `
+{{- if $pkg.Description }}
+
+{{ $pkg.Description }}
+{{- end }}
+{{- if $pkg.Authors }}
+
+Authors: {{ range $j, $a := $pkg.Authors }}{{ if $j }}, {{ end }}{{ $a }}{{ end }}
+{{- end }}
+{{- if $pkg.Organizations }}
+
+Organizations: {{ range $j, $o := $pkg.Organizations }}{{ if $j }}, {{ end }}{{ $o }}{{ end }}
+{{- end }}
+{{- if $pkg.RelatedResources }}
+
+Related resources:
+{{ range $pkg.RelatedResources }}
+- {{ . }}
+{{- end }}
+{{- end }}
+{{- if $pkg.Custom }}
+
+| Annotation | Value |
+| --- | --- |
+{{- range keys $pkg.Custom }}
+| {{ . }} | {{ custom (index $pkg.Custom .) }} |
+{{- end }}
+{{- end }}
+{{- range $pkg.Rules }}
+
+## {{ if .Title }}{{ .Title }}{{ else }}{{ .Path }}{{ end }}
+
+Rule: ` + "`{{ .Path }}`" + `{{ if .Entrypoint }} (entrypoint){{ end }}, defined at {{ .Location }}
+{{- if .Description }}
+
+{{ .Description }}
+{{- end }}
+{{- if .Authors }}
+
+Authors: {{ range $j, $a := .Authors }}{{ if $j }}, {{ end }}{{ $a }}{{ end }}
+{{- end }}
+{{- if .Custom }}
+
+| Annotation | Value |
+| --- | --- |
+{{- $custom := .Custom }}
+{{- range keys .Custom }}
+| {{ . }} | {{ custom (index $custom .) }} |
+{{- end }}
+{{- end }}
+{{- range .Schemas }}
+
+### Schema of ` + "`{{ .Path }}`" + `
+{{ if .Schema }}
+` + "`{{ .Schema }}`" + `
+{{- else }}
+` + "```json" + `
+{{ .Definition }}
+` + "```" + `
+{{- end }}
+{{- end }}
+{{- end }}
+{{ end -}}
+`))
+
+var htmlDoc = htmltemplate.Must(htmltemplate.New("html").Funcs(docFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Policy documentation</title>
+</head>
+<body>
+{{- range $pkg := . }}
+<section id="{{ $pkg.Path }}">
+<h1>{{ if $pkg.Title }}{{ $pkg.Title }}{{ else }}{{ $pkg.Path }}{{ end }}</h1>
+<p>Package: <code>{{ $pkg.Path }}</code></p>
+{{- if $pkg.Description }}
+<p>{{ $pkg.Description }}</p>
+{{- end }}
+{{- if $pkg.Authors }}
+<p>Authors: {{ range $j, $a := $pkg.Authors }}{{ if $j }}, {{ end }}{{ $a }}{{ end }}</p>
+{{- end }}
+{{- if $pkg.Organizations }}
+<p>Organizations: {{ range $j, $o := $pkg.Organizations }}{{ if $j }}, {{ end }}{{ $o }}{{ end }}</p>
+{{- end }}
+{{- if $pkg.RelatedResources }}
+<ul>
+{{- range $pkg.RelatedResources }}
+<li>{{ . }}</li>
+{{- end }}
+</ul>
+{{- end }}
+{{- if $pkg.Custom }}
+<table>
+<tr><th>Annotation</th><th>Value</th></tr>
+{{- range keys $pkg.Custom }}
+<tr><td>{{ . }}</td><td>{{ custom (index $pkg.Custom .) }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+{{- range $pkg.Rules }}
+<section id="{{ .Path }}">
+<h2>{{ if .Title }}{{ .Title }}{{ else }}{{ .Path }}{{ end }}</h2>
+<p>Rule: <code>{{ .Path }}</code>{{ if .Entrypoint }} (entrypoint){{ end }}, defined at {{ .Location }}</p>
+{{- if .Description }}
+<p>{{ .Description }}</p>
+{{- end }}
+{{- if .Authors }}
+<p>Authors: {{ range $j, $a := .Authors }}{{ if $j }}, {{ end }}{{ $a }}{{ end }}</p>
+{{- end }}
+{{- if .Custom }}
+<table>
+<tr><th>Annotation</th><th>Value</th></tr>
+{{- $custom := .Custom }}
+{{- range keys .Custom }}
+<tr><td>{{ . }}</td><td>{{ custom (index $custom .) }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+{{- range .Schemas }}
+<h3>Schema of <code>{{ .Path }}</code></h3>
+{{- if .Schema }}
+<p><code>{{ .Schema }}</code></p>
+{{- else }}
+<pre>{{ .Definition }}</pre>
+{{- end }}
+{{- end }}
+</section>
+{{- end }}
+</section>
+{{- end }}
+</body>
+</html>
+`))
+
+// RenderMarkdown writes the documentation of packages as a Markdown document.
+func RenderMarkdown(w io.Writer, docs []PackageDoc) error {
+	return errors.Wrap(markdownDoc.Execute(w, docs), "failed to render documentation")
+}
+
+// RenderHTML writes the documentation of packages as an HTML page.
+func RenderHTML(w io.Writer, docs []PackageDoc) error {
+	return errors.Wrap(htmlDoc.Execute(w, docs), "failed to render documentation")
+}
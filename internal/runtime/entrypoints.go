@@ -0,0 +1,115 @@
+package runtime
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/pkg/errors"
+)
+
+// AnnotatedEntrypoints returns the entrypoints declared with 'entrypoint: true' in the METADATA annotations of modules,
+// as slash separated paths like those given to the compiler, sorted. As for the OPA compiler, the annotations
+// of packages and documents declare entrypoints.
+func AnnotatedEntrypoints(modules []*ast.Module) ([]string, error) {
+	as, errs := ast.BuildAnnotationSet(modules)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	entrypoints := []string{}
+
+	for _, aref := range as.Flatten() {
+		if !aref.Annotations.Entrypoint {
+			continue
+		}
+
+		var path ast.Ref
+
+		switch aref.Annotations.Scope {
+		case "package":
+			if pkg := aref.GetPackage(); pkg != nil {
+				path = pkg.Path
+			}
+		case "document":
+			if rule := aref.GetRule(); rule != nil {
+				path = rule.Ref().GroundPrefix()
+			}
+		}
+
+		if path == nil {
+			continue
+		}
+
+		entrypoint, err := entrypointPath(path)
+		if err != nil {
+			return nil, err
+		}
+
+		entrypoints = append(entrypoints, entrypoint)
+	}
+
+	sort.Strings(entrypoints)
+
+	return entrypoints, nil
+}
+
+// MergeEntrypoints returns the given entrypoints followed by the annotated ones that are not given already.
+// Entrypoints are compared by the document they refer to, 'authz/allowed' and '/authz/allowed' are the same.
+func MergeEntrypoints(given, annotated []string) []string {
+	merged := []string{}
+	seen := map[string]bool{}
+
+	for _, entrypoints := range [][]string{given, annotated} {
+		for _, entrypoint := range entrypoints {
+			key := entrypoint
+
+			// invalid entrypoints are kept as given, for the compiler to report them.
+			if parsed, err := parseEntrypoint(entrypoint); err == nil {
+				if path, err := entrypointPath(parsed); err == nil {
+					key = path
+				}
+			}
+
+			if seen[key] {
+				continue
+			}
+
+			seen[key] = true
+
+			merged = append(merged, entrypoint)
+		}
+	}
+
+	return merged
+}
+
+// EntrypointQuery returns the query of the document an entrypoint refers to, as 'data.authz.allowed'.
+func EntrypointQuery(entrypoint string) (string, error) {
+	path, err := parseEntrypoint(entrypoint)
+	if err != nil {
+		return "", err
+	}
+
+	return path.String(), nil
+}
+
+// parseEntrypoint returns the reference of a slash separated entrypoint, as the OPA compiler reads it.
+func parseEntrypoint(entrypoint string) (ast.Ref, error) {
+	path, ok := storage.ParsePath("/" + strings.TrimPrefix(entrypoint, "/"))
+	if !ok {
+		return nil, errors.Errorf("invalid entrypoint [%s], use <package>/<rule>", entrypoint)
+	}
+
+	return path.Ref(ast.DefaultRootDocument), nil
+}
+
+func entrypointPath(path ast.Ref) (string, error) {
+	escaped, err := storage.NewPathForRef(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(escaped, "/"), nil
+}
@@ -0,0 +1,71 @@
+package runtime
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/format"
+	"github.com/pkg/errors"
+)
+
+type FormatParams struct {
+	Ignore      []string
+	RegoVersion RegoVersion
+}
+
+// FormattedModule is a module whose source differs from its canonical formatting.
+type FormattedModule struct {
+	File      string `json:"file"`
+	Source    []byte `json:"-"`
+	Formatted []byte `json:"-"`
+}
+
+// Format applies the canonical formatting of params.RegoVersion to the modules below paths, as the build parses them.
+// Only the modules that change are returned, files are not written.
+func Format(params *FormatParams, paths []string) ([]*FormattedModule, error) {
+	files, err := regoFiles(paths, buildCommandLoaderFilter(false, params.Ignore))
+	if err != nil {
+		return nil, err
+	}
+
+	regoVersion := params.RegoVersion.ToAstRegoVersion()
+
+	// rego.v0v1 sources are written in rego.v0 syntax, with the 'rego.v1' import.
+	parserVersion := regoVersion
+	if regoVersion == ast.RegoV0CompatV1 {
+		parserVersion = ast.RegoV0
+	}
+
+	formatted := []*FormattedModule{}
+
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		module, err := ast.ParseModuleWithOpts(file, string(src), ast.ParserOptions{
+			RegoVersion:       parserVersion,
+			ProcessAnnotation: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// the module is formatted from its syntax tree, the sources are checked by the build, not by formatting.
+		out, err := format.AstWithOpts(module, format.Opts{
+			RegoVersion:   regoVersion,
+			ParserOptions: &ast.ParserOptions{RegoVersion: parserVersion},
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to format [%s]", file)
+		}
+
+		if !bytes.Equal(out, src) {
+			formatted = append(formatted, &FormattedModule{File: file, Source: src, Formatted: out})
+		}
+	}
+
+	return formatted, nil
+}
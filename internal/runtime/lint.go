@@ -0,0 +1,362 @@
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/loader"
+	"github.com/pkg/errors"
+)
+
+const (
+	LintUnusedImport      = "unused-import"
+	LintUnusedVariable    = "unused-variable"
+	LintShadowedRule      = "shadowed-rule"
+	LintDeprecatedBuiltin = "deprecated-builtin"
+	LintMissingMetadata   = "missing-metadata"
+)
+
+// LintRule is a check applied to policy sources by Lint.
+type LintRule struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+}
+
+// LintRules are the checks of Lint, in the order they are applied.
+var LintRules = []LintRule{
+	{ID: LintUnusedImport, Description: "Imports must be used by the module."},
+	{ID: LintUnusedVariable, Description: "Variables declared in a rule must be used."},
+	{ID: LintShadowedRule, Description: "Rules and built-in functions must not be shadowed by variables or rules."},
+	{ID: LintDeprecatedBuiltin, Description: "Deprecated built-in functions must not be called."},
+	{ID: LintMissingMetadata, Description: "Packages and rules must be documented with METADATA annotations."},
+}
+
+type LintParams struct {
+	CapabilitiesJSONFile string
+	Ignore               []string
+	RegoVersion          RegoVersion
+	// Disable lists the IDs of the rules that are not applied.
+	Disable []string
+}
+
+// LintViolation is a finding of a lint rule in a module.
+type LintViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	File    string `json:"file"`
+	Row     int    `json:"row"`
+	Col     int    `json:"col"`
+}
+
+func (v *LintViolation) String() string {
+	return fmt.Sprintf("%s:%d: %s (%s)", v.File, v.Row, v.Message, v.Rule)
+}
+
+// Lint applies the lint rules to the modules below paths, violations are sorted by location.
+func Lint(params *LintParams, paths []string) ([]LintViolation, error) {
+	disabled := map[string]bool{}
+
+	for _, id := range params.Disable {
+		if !isLintRule(id) {
+			return nil, errors.Errorf("unknown lint rule [%s]", id)
+		}
+
+		disabled[id] = true
+	}
+
+	capabilities, err := LoadCapabilities(params.CapabilitiesJSONFile)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := loader.NewFileLoader().
+		WithRegoVersion(params.RegoVersion.ToAstRegoVersion()).
+		WithCapabilities(capabilities).
+		WithProcessAnnotation(true).
+		Filtered(paths, buildCommandLoaderFilter(false, params.Ignore))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(result.Modules))
+	for file := range result.Modules {
+		files = append(files, file)
+	}
+
+	sort.Strings(files)
+
+	l := &linter{
+		modules:  make([]*ast.Module, 0, len(files)),
+		rules:    map[string]map[string]bool{},
+		builtins: map[string]string{},
+	}
+
+	for _, file := range files {
+		module := result.Modules[file].Parsed
+		l.modules = append(l.modules, module)
+
+		pkg := module.Package.Path.String()
+		if l.rules[pkg] == nil {
+			l.rules[pkg] = map[string]bool{}
+		}
+
+		for _, rule := range module.Rules {
+			l.rules[pkg][ruleName(rule)] = true
+		}
+	}
+
+	for _, builtin := range capabilities.Builtins {
+		root, _, _ := strings.Cut(builtin.Name, ".")
+		if name, ok := l.builtins[root]; !ok || builtin.Name < name {
+			l.builtins[root] = builtin.Name
+		}
+	}
+
+	checks := map[string]func(*ast.Module){
+		LintUnusedImport:      l.unusedImports,
+		LintUnusedVariable:    l.unusedVariables,
+		LintShadowedRule:      l.shadowedRules,
+		LintDeprecatedBuiltin: l.deprecatedBuiltins,
+	}
+
+	for _, rule := range LintRules {
+		if disabled[rule.ID] {
+			continue
+		}
+
+		if rule.ID == LintMissingMetadata {
+			l.missingMetadata()
+			continue
+		}
+
+		for _, module := range l.modules {
+			checks[rule.ID](module)
+		}
+	}
+
+	sort.SliceStable(l.violations, func(i, j int) bool {
+		a, b := l.violations[i], l.violations[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+
+		if a.Row != b.Row {
+			return a.Row < b.Row
+		}
+
+		return a.Col < b.Col
+	})
+
+	return l.violations, nil
+}
+
+func isLintRule(id string) bool {
+	for _, rule := range LintRules {
+		if rule.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+type linter struct {
+	modules []*ast.Module
+	// rules are the names of the rules of each package.
+	rules map[string]map[string]bool
+	// builtins maps the first segment of the built-in function names to a function using it.
+	builtins map[string]string
+
+	violations []LintViolation
+}
+
+func (l *linter) report(rule string, loc *ast.Location, format string, args ...any) {
+	v := LintViolation{Rule: rule, Message: fmt.Sprintf(format, args...)}
+
+	if loc != nil {
+		v.File, v.Row, v.Col = loc.File, loc.Row, loc.Col
+	}
+
+	l.violations = append(l.violations, v)
+}
+
+func (l *linter) unusedImports(module *ast.Module) {
+	used := map[ast.Var]bool{}
+
+	for _, rule := range module.Rules {
+		ast.WalkVars(rule, func(v ast.Var) bool {
+			used[v] = true
+			return false
+		})
+	}
+
+	for _, imp := range module.Imports {
+		path, ok := imp.Path.Value.(ast.Ref)
+		if !ok || len(path) == 1 || path[0].Equal(ast.FutureRootDocument) || path[0].Equal(ast.RegoRootDocument) {
+			continue
+		}
+
+		if !used[imp.Name()] {
+			l.report(LintUnusedImport, imp.Location, "import %s is not used", imp.Path)
+		}
+	}
+}
+
+func (l *linter) unusedVariables(module *ast.Module) {
+	for _, rule := range module.Rules {
+		count := map[ast.Var]int{}
+
+		ast.WalkVars(rule, func(v ast.Var) bool {
+			count[v]++
+			return false
+		})
+
+		for _, decl := range declaredVars(rule) {
+			if count[decl.Value.(ast.Var)] == 1 {
+				l.report(LintUnusedVariable, decl.Location, "variable %s is declared but not used", decl)
+			}
+		}
+	}
+}
+
+func (l *linter) shadowedRules(module *ast.Module) {
+	pkg := module.Package.Path.String()
+
+	for _, rule := range module.Rules {
+		name := ruleName(rule)
+		if builtin, ok := l.builtins[name]; ok {
+			l.report(LintShadowedRule, rule.Location, "rule %s shadows the built-in function %s", name, builtin)
+		}
+
+		vars := declaredVars(rule)
+		for _, arg := range rule.Head.Args {
+			if _, ok := arg.Value.(ast.Var); ok {
+				vars = append(vars, arg)
+			}
+		}
+
+		for _, v := range vars {
+			if name := string(v.Value.(ast.Var)); l.rules[pkg][name] {
+				l.report(LintShadowedRule, v.Location, "variable %s shadows the rule %s.%s", name, pkg, name)
+			}
+		}
+	}
+}
+
+func (l *linter) deprecatedBuiltins(module *ast.Module) {
+	scanCalls(module, func(op ast.Ref, _ int, loc *ast.Location) {
+		if _, isRule := resolveCall(module, l.rules, op); isRule {
+			return
+		}
+
+		name := op.String()
+
+		builtin, ok := ast.BuiltinMap[name]
+		if !ok || !builtin.IsDeprecated() {
+			return
+		}
+
+		if replacement, ok := deprecatedReplacements[name]; ok {
+			l.report(LintDeprecatedBuiltin, loc, "built-in function %s is deprecated, use %s", name, replacement.Name)
+			return
+		}
+
+		l.report(LintDeprecatedBuiltin, loc, "built-in function %s is deprecated", name)
+	})
+}
+
+// missingMetadata reports the packages and the rules without METADATA annotations, tests are not reported.
+func (l *linter) missingMetadata() {
+	documented := map[string]bool{}
+	subpackages := []ast.Ref{}
+
+	for _, module := range l.modules {
+		for _, a := range module.Annotations {
+			switch a.Scope {
+			case "package":
+				documented[module.Package.Path.String()] = true
+			case "subpackages":
+				subpackages = append(subpackages, module.Package.Path)
+			}
+		}
+
+		for _, rule := range module.Rules {
+			if len(rule.Annotations) > 0 {
+				documented[rule.Path().String()] = true
+			}
+		}
+	}
+
+	reported := map[string]bool{}
+
+	for _, module := range l.modules {
+		if strings.HasSuffix(module.Package.Location.File, "_test.rego") {
+			continue
+		}
+
+		pkg := module.Package.Path
+
+		covered := documented[pkg.String()]
+		for _, prefix := range subpackages {
+			covered = covered || pkg.HasPrefix(prefix)
+		}
+
+		if !covered && !reported[pkg.String()] {
+			reported[pkg.String()] = true
+			l.report(LintMissingMetadata, module.Package.Location, "package %s has no METADATA annotation", pkg)
+		}
+
+		for _, rule := range module.Rules {
+			path := rule.Path().String()
+			if strings.HasPrefix(ruleName(rule), "_") || documented[path] || reported[path] {
+				continue
+			}
+
+			reported[path] = true
+			l.report(LintMissingMetadata, rule.Location, "rule %s has no METADATA annotation", path)
+		}
+	}
+}
+
+// declaredVars returns the variables a rule declares with ':=' and 'some', wildcards excluded.
+func declaredVars(rule *ast.Rule) []*ast.Term {
+	vars := []*ast.Term{}
+
+	add := func(t *ast.Term) {
+		ast.WalkTerms(t, func(t *ast.Term) bool {
+			if v, ok := t.Value.(ast.Var); ok && !v.IsWildcard() && !v.IsGenerated() && !strings.HasPrefix(string(v), "_") {
+				vars = append(vars, t)
+			}
+
+			return false
+		})
+	}
+
+	ast.NewGenericVisitor(func(x any) bool {
+		switch x := x.(type) {
+		case *ast.Expr:
+			if x.IsAssignment() {
+				add(x.Operand(0))
+			}
+		case *ast.SomeDecl:
+			for _, symbol := range x.Symbols {
+				// 'some x in xs' declares the key and value operands of the membership call.
+				if call, ok := symbol.Value.(ast.Call); ok {
+					for _, operand := range call[1 : len(call)-1] {
+						add(operand)
+					}
+
+					continue
+				}
+
+				add(symbol)
+			}
+		}
+
+		return false
+	}).Walk(rule)
+
+	return vars
+}
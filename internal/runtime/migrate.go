@@ -0,0 +1,240 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/open-policy-agent/opa/v1/compile"
+	"github.com/open-policy-agent/opa/v1/format"
+	"github.com/pkg/errors"
+)
+
+// deprecatedReplacements are the deprecated built-in functions with a replacement taking the same arguments.
+var deprecatedReplacements = map[string]*ast.Builtin{
+	"re_match":         ast.RegexMatch,
+	"net.cidr_overlap": ast.NetCIDRContains,
+	"set_diff":         ast.Minus,
+}
+
+type MigrateParams struct {
+	// To is the Rego version modules are rewritten to, only RegoV1 is supported.
+	To                   RegoVersion
+	Ignore               []string
+	CapabilitiesJSONFile string
+}
+
+// MigratedModule is a module rewritten by Migrate, with the changes made to its source.
+type MigratedModule struct {
+	File    string   `json:"file"`
+	Changes []string `json:"changes"`
+
+	Source   []byte `json:"-"`
+	Migrated []byte `json:"-"`
+}
+
+// Migrate rewrites the modules below paths to the syntax of params.To. Rules get the 'if' and 'contains' keywords,
+// 'future.keywords' and 'rego.v1' imports are removed and deprecated built-in functions with a replacement are
+// replaced. The migrated modules are compiled together before being returned, files are not written.
+// Only the modules that change are returned.
+func Migrate(params *MigrateParams, paths []string) ([]*MigratedModule, error) {
+	if params.To != RegoV1 {
+		return nil, errors.Errorf("migration to [%s] is not supported, only to [%s]", params.To, RegoV1)
+	}
+
+	if err := generateAllStubBuiltins(paths); err != nil {
+		return nil, err
+	}
+
+	capabilities, err := LoadCapabilities(params.CapabilitiesJSONFile)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := regoFiles(paths, buildCommandLoaderFilter(false, params.Ignore))
+	if err != nil {
+		return nil, err
+	}
+
+	migrated := []*MigratedModule{}
+	b := &bundle.Bundle{Data: map[string]any{}}
+
+	for _, file := range files {
+		m, parsed, err := migrateFile(file, capabilities)
+		if err != nil {
+			return nil, err
+		}
+
+		b.Modules = append(b.Modules, bundle.ModuleFile{URL: file, Path: file, Raw: m.Migrated, Parsed: parsed})
+
+		if len(m.Changes) > 0 {
+			migrated = append(migrated, m)
+		}
+	}
+
+	// the migrated modules must compile as they would in a build.
+	compiler := compile.New().
+		WithCapabilities(capabilities).
+		WithRegoVersion(params.To.ToAstRegoVersion()).
+		WithBundle(b)
+
+	if err := compiler.Build(context.Background()); err != nil {
+		return nil, errors.Wrap(err, "migrated modules do not compile")
+	}
+
+	return migrated, nil
+}
+
+func migrateFile(file string, capabilities *ast.Capabilities) (*MigratedModule, *ast.Module, error) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m := &MigratedModule{File: file, Source: src, Migrated: src, Changes: []string{}}
+
+	// modules already written in rego.v1 are parsed as such, a module like 'f(x) := x if { x }' also parses as
+	// rego.v0 with a different meaning.
+	version := ast.RegoV1
+
+	module, err := ast.ParseModuleWithOpts(file, string(src), ast.ParserOptions{
+		RegoVersion:       version,
+		ProcessAnnotation: true,
+		Capabilities:      capabilities,
+	})
+	if err != nil {
+		version = ast.RegoV0
+
+		module, err = ast.ParseModuleWithOpts(file, string(src), ast.ParserOptions{
+			RegoVersion:       version,
+			ProcessAnnotation: true,
+			Capabilities:      capabilities,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, imp := range module.Imports {
+		if path, ok := imp.Path.Value.(ast.Ref); ok && (path[0].Equal(ast.FutureRootDocument) || path[0].Equal(ast.RegoRootDocument)) {
+			m.Changes = append(m.Changes, fmt.Sprintf("%s: removed import %s", location(imp.Location), path))
+		}
+	}
+
+	m.Changes = append(m.Changes, replaceDeprecated(module)...)
+
+	migrated, err := format.AstWithOpts(module, format.Opts{
+		RegoVersion:   ast.RegoV1,
+		DropV0Imports: true,
+		ParserOptions: &ast.ParserOptions{RegoVersion: version},
+		Capabilities:  capabilities,
+	})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to format [%s]", file)
+	}
+
+	// the rewritten syntax is parsed again, as the compiler would.
+	parsed, err := ast.ParseModuleWithOpts(file, string(migrated), ast.ParserOptions{
+		RegoVersion:       ast.RegoV1,
+		ProcessAnnotation: true,
+		Capabilities:      capabilities,
+	})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "migrated module [%s] does not parse", file)
+	}
+
+	if bytes.Equal(migrated, src) {
+		return m, parsed, nil
+	}
+
+	m.Changes = append(m.Changes, "rewrote the module in rego.v1 syntax, with the 'if' and 'contains' keywords")
+	m.Migrated = migrated
+
+	return m, parsed, nil
+}
+
+// replaceDeprecated replaces the calls to deprecated built-in functions that have a replacement.
+func replaceDeprecated(module *ast.Module) []string {
+	changes := []string{}
+
+	replace := func(op *ast.Term) {
+		ref, ok := op.Value.(ast.Ref)
+		if !ok {
+			return
+		}
+
+		name := ref.String()
+
+		replacement, ok := deprecatedReplacements[name]
+		if !ok {
+			return
+		}
+
+		op.Value = replacement.Ref()
+		changes = append(changes, fmt.Sprintf("%s: replaced deprecated %s with %s", location(op.Location), name, replacement.Name))
+	}
+
+	ast.NewGenericVisitor(func(x any) bool {
+		switch x := x.(type) {
+		case *ast.Expr:
+			if terms, ok := x.Terms.([]*ast.Term); ok && len(terms) > 0 {
+				replace(terms[0])
+			}
+		case ast.Call:
+			replace(x[0])
+		}
+
+		return false
+	}).Walk(module)
+
+	return changes
+}
+
+// regoFiles returns the Rego files below paths that are not excluded by filter, sorted by name.
+func regoFiles(paths []string, filter func(string, os.FileInfo, int) bool) ([]string, error) {
+	files := []string{}
+
+	for _, root := range paths {
+		err := filepath.Walk(root, func(path string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if filter(path, info, depth(root, path)) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+
+			if info.Mode().IsRegular() && strings.HasSuffix(path, ".rego") {
+				files = append(files, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read [%s]", root)
+		}
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func location(loc *ast.Location) string {
+	if loc == nil {
+		return "-"
+	}
+
+	return fmt.Sprintf("%s:%d", loc.File, loc.Row)
+}
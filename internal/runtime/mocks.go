@@ -0,0 +1,335 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// MockFile is the YAML or JSON document describing the responses of stub builtins.
+//
+//	mocks:
+//	  ds.check_relation:
+//	    value: true
+//	  ds.user:
+//	    table:
+//	      - args: [{"id": "alice"}]
+//	        value: {"name": "Alice"}
+//	    value: null
+//	  ds.check_permission:
+//	    rego: data.mocks.check_permission
+//	module: |
+//	  package mocks
+//	  check_permission(req) := req.permission == "read"
+type MockFile struct {
+	Mocks map[string]MockSpec `json:"mocks"`
+	// Module is a Rego module defining the functions referred to by rego mocks.
+	Module string `json:"module,omitempty"`
+}
+
+// MockSpec is the response of a stub builtin: the value of the first table row whose arguments equal the call
+// arguments, the result of a Rego function called with the same arguments, or a fixed value.
+type MockSpec struct {
+	// Value is the fixed response, or the default response when no table row matches.
+	Value json.RawMessage `json:"value,omitempty"`
+	Table []MockCase      `json:"table,omitempty"`
+	Rego  string          `json:"rego,omitempty"`
+}
+
+type MockCase struct {
+	Args  []any `json:"args"`
+	Value any   `json:"value"`
+}
+
+// Mocks answers the calls to stub builtins while evaluating with a context returned by WithMocks.
+type Mocks struct {
+	mocks       map[string]*mock
+	module      *ast.Module
+	regoVersion RegoVersion
+
+	mu       sync.Mutex
+	unmocked map[string]bool
+}
+
+type mock struct {
+	value *ast.Term
+	table []mockCase
+	rego  ast.Ref
+
+	queries map[int]*rego.PreparedEvalQuery
+}
+
+type mockCase struct {
+	args  []*ast.Term
+	value *ast.Term
+}
+
+type mocksKey struct{}
+
+// WithMocks returns a context in which stub builtins are answered by mocks.
+func WithMocks(ctx context.Context, mocks *Mocks) context.Context {
+	if mocks == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, mocksKey{}, mocks)
+}
+
+func mocksFrom(ctx context.Context) *Mocks {
+	if ctx == nil {
+		return nil
+	}
+
+	mocks, _ := ctx.Value(mocksKey{}).(*Mocks)
+
+	return mocks
+}
+
+// LoadMocks reads a mock file.
+func LoadMocks(path string, regoVersion RegoVersion) (*Mocks, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read mocks [%s]", path)
+	}
+
+	file := MockFile{}
+	if err := yaml.Unmarshal(buf, &file); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse mocks [%s]", path)
+	}
+
+	mocks, err := NewMocks(&file, regoVersion)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid mocks [%s]", path)
+	}
+
+	return mocks, nil
+}
+
+// NewMocks validates a mock file, values are converted and the module parsed once.
+func NewMocks(file *MockFile, regoVersion RegoVersion) (*Mocks, error) {
+	mocks := &Mocks{
+		mocks:       map[string]*mock{},
+		regoVersion: regoVersion,
+		unmocked:    map[string]bool{},
+	}
+
+	if file.Module != "" {
+		module, err := ast.ParseModuleWithOpts("mocks.rego", file.Module, ast.ParserOptions{
+			RegoVersion: regoVersion.ToAstRegoVersion(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		mocks.module = module
+	}
+
+	for name, spec := range file.Mocks {
+		m := &mock{queries: map[int]*rego.PreparedEvalQuery{}}
+
+		if spec.Value != nil {
+			var value any
+			if err := json.Unmarshal(spec.Value, &value); err != nil {
+				return nil, errors.Wrapf(err, "mock [%s]", name)
+			}
+
+			term, err := toTerm(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "mock [%s]", name)
+			}
+
+			m.value = term
+		}
+
+		for i, row := range spec.Table {
+			c := mockCase{}
+
+			for _, arg := range row.Args {
+				term, err := toTerm(arg)
+				if err != nil {
+					return nil, errors.Wrapf(err, "mock [%s] row %d", name, i+1)
+				}
+
+				c.args = append(c.args, term)
+			}
+
+			term, err := toTerm(row.Value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "mock [%s] row %d", name, i+1)
+			}
+
+			c.value = term
+			m.table = append(m.table, c)
+		}
+
+		if spec.Rego != "" {
+			if mocks.module == nil {
+				return nil, errors.Errorf("mock [%s] refers to [%s] but no module is defined", name, spec.Rego)
+			}
+
+			ref, err := ast.ParseRef(spec.Rego)
+			if err != nil {
+				return nil, errors.Wrapf(err, "mock [%s]", name)
+			}
+
+			m.rego = ref
+		}
+
+		if m.value == nil && m.table == nil && m.rego == nil {
+			return nil, errors.Errorf("mock [%s] has no value, table or rego function", name)
+		}
+
+		mocks.mocks[name] = m
+	}
+
+	return mocks, nil
+}
+
+// Unmocked returns the stub builtins called without a mock or a matching table row, sorted by name.
+func (m *Mocks) Unmocked() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.unmocked))
+	for name := range m.unmocked {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func (m *Mocks) call(bctx rego.BuiltinContext, name string, args []*ast.Term) (*ast.Term, error) {
+	mock, ok := m.mocks[name]
+	if !ok {
+		m.report(name)
+		return ast.NullTerm(), nil
+	}
+
+	for _, c := range mock.table {
+		if argsEqual(c.args, args) {
+			return c.value, nil
+		}
+	}
+
+	if mock.rego != nil {
+		return m.eval(bctx, mock, args)
+	}
+
+	if mock.value != nil {
+		return mock.value, nil
+	}
+
+	m.report(fmt.Sprintf("%s(%s)", name, joinTerms(args)))
+
+	return ast.NullTerm(), nil
+}
+
+// eval calls the Rego function of a mock, the query for each number of arguments is prepared once.
+func (m *Mocks) eval(bctx rego.BuiltinContext, mock *mock, args []*ast.Term) (*ast.Term, error) {
+	m.mu.Lock()
+	query, ok := mock.queries[len(args)]
+	m.mu.Unlock()
+
+	if !ok {
+		call := ast.Call(append([]*ast.Term{ast.RefTerm(mock.rego...)}, inputArgs(len(args))...))
+
+		prepared, err := rego.New(
+			rego.ParsedQuery(ast.NewBody(ast.Equality.Expr(ast.VarTerm("result"), ast.NewTerm(call)))),
+			rego.ParsedModule(m.module),
+			rego.SetRegoVersion(m.regoVersion.ToAstRegoVersion()),
+		).PrepareForEval(bctx.Context)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to prepare mock [%s]", mock.rego)
+		}
+
+		query = &prepared
+
+		m.mu.Lock()
+		mock.queries[len(args)] = query
+		m.mu.Unlock()
+	}
+
+	input := make([]*ast.Term, len(args))
+	copy(input, args)
+
+	rs, err := query.Eval(bctx.Context, rego.EvalParsedInput(ast.NewArray(input...)))
+	if err != nil {
+		return nil, err
+	}
+
+	// like a function without a result, an undefined mock leaves the call undefined.
+	if len(rs) == 0 {
+		return nil, nil
+	}
+
+	return toTerm(rs[0].Bindings["result"])
+}
+
+func (m *Mocks) report(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.unmocked[name] = true
+}
+
+// stubCall answers a call to a stub builtin with the mocks of the evaluation, or null.
+func stubCall(bctx rego.BuiltinContext, name string, args ...*ast.Term) (*ast.Term, error) {
+	mocks := mocksFrom(bctx.Context)
+	if mocks == nil {
+		return ast.NullTerm(), nil
+	}
+
+	return mocks.call(bctx, name, args)
+}
+
+func inputArgs(n int) []*ast.Term {
+	args := make([]*ast.Term, n)
+	for i := range args {
+		args[i] = ast.NewTerm(ast.InputRootRef.Append(ast.InternedTerm(i)))
+	}
+
+	return args
+}
+
+func argsEqual(a, b []*ast.Term) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func joinTerms(terms []*ast.Term) string {
+	s := make([]string, len(terms))
+	for i, term := range terms {
+		s[i] = term.String()
+	}
+
+	return strings.Join(s, ", ")
+}
+
+func toTerm(v any) (*ast.Term, error) {
+	value, err := ast.InterfaceToValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.NewTerm(value), nil
+}
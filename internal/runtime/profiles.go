@@ -0,0 +1,47 @@
+package runtime
+
+import (
+	"path"
+	"sort"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/pkg/errors"
+)
+
+// DenyBuiltins returns a copy of capabilities without the built-in functions matching the deny patterns,
+// names like 'http.send' or globs like 'net.*', and the sorted names of the removed built-ins.
+func DenyBuiltins(capabilities *ast.Capabilities, deny []string) (*ast.Capabilities, []string, error) {
+	for _, pattern := range deny {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid deny pattern [%s]", pattern)
+		}
+	}
+
+	restricted := *capabilities
+	restricted.Builtins = make([]*ast.Builtin, 0, len(capabilities.Builtins))
+
+	denied := []string{}
+
+	for _, builtin := range capabilities.Builtins {
+		if builtinDenied(builtin.Name, deny) {
+			denied = append(denied, builtin.Name)
+			continue
+		}
+
+		restricted.Builtins = append(restricted.Builtins, builtin)
+	}
+
+	sort.Strings(denied)
+
+	return &restricted, denied, nil
+}
+
+func builtinDenied(name string, deny []string) bool {
+	for _, pattern := range deny {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
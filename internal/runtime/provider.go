@@ -0,0 +1,317 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/topdown"
+	"github.com/open-policy-agent/opa/v1/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// providerShutdownTimeout is how long a provider has to exit once its stdin is closed before it is killed.
+const providerShutdownTimeout = 5 * time.Second
+
+const (
+	providerMethodDeclare = "builtins.declare"
+	providerMethodCall    = "builtins.call"
+)
+
+// ProviderConfig names an external executable implementing custom builtins.
+type ProviderConfig struct {
+	Name    string
+	Command string
+	Args    []string
+	Env     []string
+}
+
+// Provider is a running builtin provider. It speaks JSON-RPC 2.0 over its stdin and stdout, one message per line:
+// 'builtins.declare' returns the builtins it implements as {"builtins": [{"name": ..., "decl": ...}]}, with the
+// declarations in the format of the required builtins of a manifest, and 'builtins.call' with
+// {"name": ..., "args": [...]} returns {"value": ...}, or no value when the call is undefined.
+type Provider struct {
+	name   string
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *json.Decoder
+
+	mu     sync.Mutex
+	nextID int
+
+	builtins []ProvidedBuiltin
+}
+
+// ProvidedBuiltin is a builtin declared by a provider.
+type ProvidedBuiltin struct {
+	Name             string         `json:"name"`
+	Decl             types.Function `json:"decl"`
+	Nondeterministic bool           `json:"nondeterministic,omitempty"`
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int    `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int             `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *rpcError       `json:"error"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+type callParams struct {
+	Name string `json:"name"`
+	Args []any  `json:"args"`
+}
+
+type callResult struct {
+	Value json.RawMessage `json:"value"`
+}
+
+// StartProvider starts the executable of a provider and asks for the builtins it declares.
+func StartProvider(ctx context.Context, logger *zerolog.Logger, cfg *ProviderConfig) (*Provider, error) {
+	providerLogger := logger.With().Str("provider", cfg.Name).Logger()
+
+	cmd := exec.CommandContext(ctx, cfg.Command, cfg.Args...) //nolint:gosec // the command comes from the user config.
+	cmd.Env = append(os.Environ(), cfg.Env...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrapf(err, "failed to start builtin provider [%s]", cfg.Name)
+	}
+
+	decoder := json.NewDecoder(bufio.NewReader(stdout))
+	decoder.UseNumber()
+
+	p := &Provider{
+		name:   cfg.Name,
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: decoder,
+	}
+
+	declared := struct {
+		Builtins []ProvidedBuiltin `json:"builtins"`
+	}{}
+
+	if err := p.request(providerMethodDeclare, nil, &declared); err != nil {
+		_ = p.Close()
+		return nil, err
+	}
+
+	p.builtins = declared.Builtins
+
+	providerLogger.Debug().Int("builtins", len(p.builtins)).Msg("Builtin provider started.")
+
+	return p, nil
+}
+
+// Name returns the name of the provider in the config.
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// Builtins returns the builtins declared by the provider.
+func (p *Provider) Builtins() []ProvidedBuiltin {
+	return p.builtins
+}
+
+// Call calls a builtin of the provider, a nil term is an undefined result.
+func (p *Provider) Call(name string, args []*ast.Term) (*ast.Term, error) {
+	params := callParams{Name: name, Args: make([]any, len(args))}
+
+	for i, arg := range args {
+		value, err := ast.JSON(arg.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "argument %d of [%s]", i+1, name)
+		}
+
+		params.Args[i] = value
+	}
+
+	result := callResult{}
+	if err := p.request(providerMethodCall, params, &result); err != nil {
+		// like the errors of OPA builtins, errors answered by the provider leave the call undefined,
+		// a provider that can no longer be reached stops the evaluation.
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			return nil, err
+		}
+
+		return nil, topdown.Halt{Err: err}
+	}
+
+	if result.Value == nil {
+		return nil, nil
+	}
+
+	value, err := ast.ValueFromReader(bytes.NewReader(result.Value))
+	if err != nil {
+		return nil, errors.Wrapf(err, "builtin provider [%s] returned an invalid value for [%s]", p.name, name)
+	}
+
+	return ast.NewTerm(value), nil
+}
+
+// Close closes the stdin of the provider and waits for it to exit, killing it after a timeout.
+func (p *Provider) Close() error {
+	_ = p.stdin.Close()
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- p.cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(providerShutdownTimeout):
+		_ = p.cmd.Process.Kill()
+		return errors.Errorf("builtin provider [%s] did not exit", p.name)
+	}
+}
+
+// request sends a request and reads its response, requests are answered one at a time in order.
+func (p *Provider) request(method string, params, result any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.nextID++
+	req := rpcRequest{JSONRPC: "2.0", ID: p.nextID, Method: method, Params: params}
+
+	buf, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.stdin.Write(append(buf, '\n')); err != nil {
+		return errors.Wrapf(err, "failed to send [%s] to builtin provider [%s]", method, p.name)
+	}
+
+	resp := rpcResponse{}
+	if err := p.stdout.Decode(&resp); err != nil {
+		return errors.Wrapf(err, "failed to read [%s] response of builtin provider [%s]", method, p.name)
+	}
+
+	if resp.ID != req.ID {
+		return errors.Errorf("builtin provider [%s] answered request %d instead of %d", p.name, resp.ID, req.ID)
+	}
+
+	if resp.Error != nil {
+		return errors.Wrapf(resp.Error, "builtin provider [%s]", p.name)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return errors.Wrapf(err, "invalid [%s] response of builtin provider [%s]", method, p.name)
+	}
+
+	return nil
+}
+
+// AddProvider adds the builtins declared by a provider to the runtime, see RegisterBuiltins.
+func (r *Runtime) AddProvider(p *Provider) {
+	for _, b := range p.Builtins() {
+		builtin := b
+
+		fn := &rego.Function{
+			Name:             builtin.Name,
+			Decl:             &builtin.Decl,
+			Nondeterministic: builtin.Nondeterministic,
+		}
+
+		call := func(args ...*ast.Term) (*ast.Term, error) {
+			return p.Call(builtin.Name, args)
+		}
+
+		switch len(builtin.Decl.FuncArgs().Args) {
+		case 1:
+			r.builtins1[fn] = func(_ rego.BuiltinContext, op1 *ast.Term) (*ast.Term, error) {
+				return call(op1)
+			}
+		case 2:
+			r.builtins2[fn] = func(_ rego.BuiltinContext, op1, op2 *ast.Term) (*ast.Term, error) {
+				return call(op1, op2)
+			}
+		case 3:
+			r.builtins3[fn] = func(_ rego.BuiltinContext, op1, op2, op3 *ast.Term) (*ast.Term, error) {
+				return call(op1, op2, op3)
+			}
+		case 4:
+			r.builtins4[fn] = func(_ rego.BuiltinContext, op1, op2, op3, op4 *ast.Term) (*ast.Term, error) {
+				return call(op1, op2, op3, op4)
+			}
+		default:
+			r.builtinsDyn[fn] = func(_ rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
+				return call(terms...)
+			}
+		}
+
+		r.compilerBuiltins[builtin.Name] = &ast.Builtin{
+			Name:             builtin.Name,
+			Decl:             &builtin.Decl,
+			Nondeterministic: builtin.Nondeterministic,
+		}
+	}
+}
+
+// RegisterBuiltins registers the builtins of the runtime for every evaluation of the process. They take precedence
+// over the stubs of required builtins, which are only registered for names without an implementation.
+func (r *Runtime) RegisterBuiltins() {
+	for fn, impl := range r.builtins1 {
+		rego.RegisterBuiltin1(fn, impl)
+	}
+
+	for fn, impl := range r.builtins2 {
+		rego.RegisterBuiltin2(fn, impl)
+	}
+
+	for fn, impl := range r.builtins3 {
+		rego.RegisterBuiltin3(fn, impl)
+	}
+
+	for fn, impl := range r.builtins4 {
+		rego.RegisterBuiltin4(fn, impl)
+	}
+
+	for fn, impl := range r.builtinsDyn {
+		rego.RegisterBuiltinDyn(fn, impl)
+	}
+}
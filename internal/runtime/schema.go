@@ -0,0 +1,181 @@
+package runtime
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/loader"
+	"github.com/open-policy-agent/opa/v1/util"
+	"github.com/pkg/errors"
+)
+
+// Schemas are JSON schemas by the reference METADATA annotations use for them, as 'schema.input' for the file
+// input.json of a schema directory, or 'schema' for a single schema file, which applies to the input.
+type Schemas map[string]any
+
+// LoadSchemas reads the JSON (or YAML) schemas of a directory or of a single file.
+func LoadSchemas(path string) (Schemas, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := Schemas{}
+
+	if !info.IsDir() {
+		schema, err := readSchema(path)
+		if err != nil {
+			return nil, err
+		}
+
+		schemas[ast.SchemaRootRef.String()] = schema
+
+		return schemas, nil
+	}
+
+	err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		schema, err := readSchema(file)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(path, file)
+		if err != nil {
+			return err
+		}
+
+		// as OPA, 'a/b.json' is the schema 'schema.a.b'.
+		parts := strings.Split(filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel))), "/")
+
+		key := ast.Ref{ast.SchemaRootDocument}
+		for _, part := range parts {
+			key = append(key, ast.StringTerm(part))
+		}
+
+		schemas[key.String()] = schema
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read schemas [%s]", path)
+	}
+
+	return schemas, nil
+}
+
+func readSchema(file string) (any, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var schema any
+	if err := util.Unmarshal(buf, &schema); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse schema [%s]", file)
+	}
+
+	return schema, nil
+}
+
+// SchemaSet returns the schemas for the type checker of the OPA compiler, nil when there are none.
+func (s Schemas) SchemaSet() (*ast.SchemaSet, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	ss := ast.NewSchemaSet()
+
+	for key, schema := range s {
+		ref, err := schemaRef(key)
+		if err != nil {
+			return nil, err
+		}
+
+		ss.Put(ref, schema)
+	}
+
+	return ss, nil
+}
+
+// schemaRef parses the reference of a schema, the root 'schema' parses as a variable.
+func schemaRef(key string) (ast.Ref, error) {
+	term, err := ast.ParseTerm(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid schema reference [%s]", key)
+	}
+
+	switch v := term.Value.(type) {
+	case ast.Ref:
+		return v, nil
+	case ast.Var:
+		return ast.Ref{term}, nil
+	default:
+		return nil, errors.Errorf("invalid schema reference [%s]", key)
+	}
+}
+
+// Refs returns the sorted references of the schemas.
+func (s Schemas) Refs() []string {
+	refs := make([]string, 0, len(s))
+	for ref := range s {
+		refs = append(refs, ref)
+	}
+
+	sort.Strings(refs)
+
+	return refs
+}
+
+// checkSchemas type-checks the modules below paths against the schemas, those given and those declared in METADATA
+// annotations, and returns the type errors with their locations. Nothing is checked when there are no schemas.
+func checkSchemas(params *BuildParams, capabilities *ast.Capabilities, paths []string) error {
+	result, err := loader.NewFileLoader().
+		WithRegoVersion(params.RegoVersion.ToAstRegoVersion()).
+		WithCapabilities(capabilities).
+		WithProcessAnnotation(true).
+		Filtered(paths, buildCommandLoaderFilter(true, params.Ignore))
+	if err != nil {
+		return err
+	}
+
+	if len(params.Schemas) == 0 && !declaresSchemas(result.ParsedModules()) {
+		return nil
+	}
+
+	ss, err := params.Schemas.SchemaSet()
+	if err != nil {
+		return err
+	}
+
+	compiler := ast.NewCompiler().
+		WithCapabilities(capabilities).
+		WithSchemas(ss).
+		WithUseTypeCheckAnnotations(true).
+		WithDefaultRegoVersion(params.RegoVersion.ToAstRegoVersion())
+
+	if compiler.Compile(result.ParsedModules()); compiler.Failed() {
+		return compiler.Errors
+	}
+
+	return nil
+}
+
+func declaresSchemas(modules map[string]*ast.Module) bool {
+	for _, module := range modules {
+		for _, a := range module.Annotations {
+			if len(a.Schemas) > 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
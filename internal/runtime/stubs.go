@@ -35,37 +35,59 @@ type StubBuiltinDefs struct {
 
 func generateAllStubBuiltins(paths []string) error {
 	for _, path := range paths {
-		manifestPath := filepath.Join(path, ".manifest")
-
-		manifestExists, err := fileExists(manifestPath)
+		defs, err := readManifestBuiltins(path)
 		if err != nil {
-			return errors.Wrapf(err, "failed to determine if file [%s] exists", manifestPath)
+			return err
 		}
 
-		if !manifestExists {
-			continue
+		if defs != nil {
+			registerStubBuiltins(defs)
 		}
+	}
 
-		manifestBytes, err := os.ReadFile(manifestPath)
-		if err != nil {
-			return errors.Wrapf(err, "failed to read manifest [%s]", manifestPath)
-		}
+	return nil
+}
 
-		manifest := MetadataEx{}
+// readManifestBuiltins returns the required builtins declared in the .manifest of a build path, if any.
+func readManifestBuiltins(path string) (*StubBuiltinDefs, error) {
+	// a file given as a path has no manifest.
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return nil, nil
+	}
 
-		if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
-			return errors.Wrapf(err, "failed to unmarshal json from manifest [%s]", manifestPath)
-		}
+	return readManifestBuiltinsFile(filepath.Join(path, ".manifest"))
+}
 
-		if manifest.Metadata.RequiredBuiltins != nil {
-			registerStubBuiltins(manifest.Metadata.RequiredBuiltins)
-		}
+func readManifestBuiltinsFile(manifestPath string) (*StubBuiltinDefs, error) {
+	manifestExists, err := fileExists(manifestPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to determine if file [%s] exists", manifestPath)
 	}
 
-	return nil
+	if !manifestExists {
+		return nil, nil
+	}
+
+	manifestBytes, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read manifest [%s]", manifestPath)
+	}
+
+	manifest := MetadataEx{}
+
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal json from manifest [%s]", manifestPath)
+	}
+
+	return manifest.Metadata.RequiredBuiltins, nil
 }
 
 func RegisterStubBuiltins(defs *StubBuiltinDefs) {
+	// bundles without required builtins have nothing to register.
+	if defs == nil {
+		return
+	}
+
 	registerStubBuiltins(defs)
 }
 
@@ -81,8 +103,8 @@ func registerStubBuiltins(defs *StubBuiltinDefs) {
 			Name:    builtin.Name,
 			Memoize: false,
 			Decl:    &builtin.Decl,
-		}, func(rego.BuiltinContext, *ast.Term) (*ast.Term, error) {
-			return ast.NullTerm(), nil
+		}, func(bctx rego.BuiltinContext, op1 *ast.Term) (*ast.Term, error) {
+			return stubCall(bctx, builtin.Name, op1)
 		})
 	}
 
@@ -98,7 +120,7 @@ func registerStubBuiltins(defs *StubBuiltinDefs) {
 			Memoize: false,
 			Decl:    &builtin.Decl,
 		}, func(bctx rego.BuiltinContext, op1, op2 *ast.Term) (*ast.Term, error) {
-			return ast.NullTerm(), nil
+			return stubCall(bctx, builtin.Name, op1, op2)
 		})
 	}
 
@@ -114,7 +136,7 @@ func registerStubBuiltins(defs *StubBuiltinDefs) {
 			Memoize: false,
 			Decl:    &builtin.Decl,
 		}, func(bctx rego.BuiltinContext, op1, op2, op3 *ast.Term) (*ast.Term, error) {
-			return ast.NullTerm(), nil
+			return stubCall(bctx, builtin.Name, op1, op2, op3)
 		})
 	}
 
@@ -130,7 +152,7 @@ func registerStubBuiltins(defs *StubBuiltinDefs) {
 			Memoize: false,
 			Decl:    &builtin.Decl,
 		}, func(bctx rego.BuiltinContext, op1, op2, op3, op4 *ast.Term) (*ast.Term, error) {
-			return ast.NullTerm(), nil
+			return stubCall(bctx, builtin.Name, op1, op2, op3, op4)
 		})
 	}
 
@@ -146,7 +168,7 @@ func registerStubBuiltins(defs *StubBuiltinDefs) {
 			Memoize: false,
 			Decl:    &builtin.Decl,
 		}, func(bctx rego.BuiltinContext, terms []*ast.Term) (*ast.Term, error) {
-			return ast.NullTerm(), nil
+			return stubCall(bctx, builtin.Name, terms...)
 		})
 	}
 }
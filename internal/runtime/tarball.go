@@ -0,0 +1,232 @@
+package runtime
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/pkg/errors"
+)
+
+// gzipOSUnknown is the OS byte written in the gzip header, independent of the build host.
+const gzipOSUnknown = 255
+
+// tarEntry is a file of a bundle tarball, its content is written to the tarball once the entries are sorted.
+type tarEntry struct {
+	name  string
+	size  int64
+	write func(io.Writer) error
+}
+
+// writeBundle writes a bundle as a reproducible tarball, the same bundle always produces the same bytes:
+// entries are sorted by name, headers carry no timestamps or ownership and the gzip header is fixed.
+func writeBundle(w io.Writer, b *bundle.Bundle) error {
+	entries, err := bundleEntries(b)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+
+	gw, err := gzip.NewWriterLevel(w, gzip.DefaultCompression)
+	if err != nil {
+		return err
+	}
+
+	gw.Header = gzip.Header{ModTime: time.Time{}, OS: gzipOSUnknown}
+
+	tw := tar.NewWriter(gw)
+
+	for _, entry := range entries {
+		hdr := &tar.Header{
+			Name:     entry.name,
+			Mode:     0o600,
+			Typeflag: tar.TypeReg,
+			Size:     entry.size,
+			ModTime:  time.Unix(0, 0),
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return errors.Wrapf(err, "failed to write tar header for [%s]", entry.name)
+		}
+
+		if err := entry.write(tw); err != nil {
+			return errors.Wrapf(err, "failed to write [%s] to tarball", entry.name)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gw.Close()
+}
+
+// bundleEntries lists the files of a snapshot bundle, as written by the OPA bundle writer.
+func bundleEntries(b *bundle.Bundle) ([]tarEntry, error) {
+	data, err := jsonEntry("/data.json", b.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []tarEntry{data}
+
+	for _, module := range b.Modules {
+		entries = append(entries, rawEntry(path.Join("/", module.URL), module.Raw))
+	}
+
+	for _, module := range b.WasmModules {
+		entries = append(entries, rawEntry(path.Join("/", module.URL), module.Raw))
+	}
+
+	if len(b.Wasm) > 0 {
+		entries = append(entries, rawEntry("/"+bundle.WasmFile, b.Wasm))
+	}
+
+	for _, module := range b.PlanModules {
+		entries = append(entries, rawEntry(path.Join("/", module.URL), module.Raw))
+	}
+
+	if b.Signatures.Signatures != nil || b.Signatures.Plugin != "" {
+		signatures, err := json.MarshalIndent(b.Signatures, "", " ")
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, rawEntry("/."+bundle.SignaturesFile, signatures))
+	}
+
+	if !b.Manifest.Empty() {
+		manifest, err := jsonEntry("/"+bundle.ManifestExt, b.Manifest)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, manifest)
+	}
+
+	return entries, nil
+}
+
+// rawEntry is a file whose content the bundle already holds.
+func rawEntry(name string, data []byte) tarEntry {
+	return tarEntry{
+		name: name,
+		size: int64(len(data)),
+		write: func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		},
+	}
+}
+
+// jsonEntry is a file encoding v as JSON. The size of the document is counted by encoding it a first time, it is
+// encoded again into the tarball.
+func jsonEntry(name string, v any) (tarEntry, error) {
+	counter := &countingWriter{}
+	if err := encodeJSON(counter, v); err != nil {
+		return tarEntry{}, errors.Wrapf(err, "failed to encode [%s]", name)
+	}
+
+	return tarEntry{
+		name: name,
+		size: counter.n,
+		write: func(w io.Writer) error {
+			return encodeJSON(w, v)
+		},
+	}, nil
+}
+
+// encodeJSON writes v as a json.Encoder does. Objects and arrays are written member by member, only their other
+// values are encoded in memory.
+func encodeJSON(w io.Writer, v any) error {
+	if err := writeJSON(w, v); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
+
+func writeJSON(w io.Writer, v any) error {
+	switch x := v.(type) {
+	case map[string]any:
+		if x == nil {
+			break
+		}
+
+		// keys are sorted as encoding/json sorts them.
+		keys := slices.Sorted(maps.Keys(x))
+
+		return writeMembers(w, "{", "}", len(keys), func(i int) error {
+			key, err := json.Marshal(keys[i])
+			if err != nil {
+				return err
+			}
+
+			if _, err := w.Write(append(key, ':')); err != nil {
+				return err
+			}
+
+			return writeJSON(w, x[keys[i]])
+		})
+	case []any:
+		if x == nil {
+			break
+		}
+
+		return writeMembers(w, "[", "]", len(x), func(i int) error {
+			return writeJSON(w, x[i])
+		})
+	}
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(buf)
+
+	return err
+}
+
+func writeMembers(w io.Writer, open, closing string, n int, member func(int) error) error {
+	if _, err := io.WriteString(w, open); err != nil {
+		return err
+	}
+
+	for i := range n {
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+
+		if err := member(i); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, closing)
+
+	return err
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,97 @@
+package runtime
+
+import (
+	"context"
+	"sort"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/cover"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/tester"
+)
+
+type TestParams struct {
+	CapabilitiesJSONFile string
+	Ignore               []string
+	RegoVersion          RegoVersion
+	// Run selects the tests to run by a regular expression on their package and name.
+	Run   string
+	Mocks *Mocks
+	// Schemas type-check the modules, with the schemas declared in their METADATA annotations.
+	Schemas Schemas
+	// Coverage traces the evaluation of the tests to report the line coverage of the modules.
+	Coverage bool
+}
+
+// Test runs the Rego tests of the policy sources below paths, with stubs for the builtins their manifests require.
+// With coverage, it also returns the line coverage of the modules other than tests.
+func Test(ctx context.Context, params *TestParams, paths []string) ([]*tester.Result, *cover.Report, error) {
+	if err := generateAllStubBuiltins(paths); err != nil {
+		return nil, nil, err
+	}
+
+	capabilities, err := LoadCapabilities(params.CapabilitiesJSONFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	modules, store, err := tester.LoadWithRegoVersion(paths, buildCommandLoaderFilter(false, params.Ignore),
+		params.RegoVersion.ToAstRegoVersion())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	txn, err := store.NewTransaction(ctx, storage.WriteParams)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer store.Abort(ctx, txn)
+
+	ss, err := params.Schemas.SchemaSet()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	compiler := ast.NewCompiler().
+		WithCapabilities(capabilities).
+		WithEnablePrintStatements(true).
+		WithSchemas(ss).
+		WithUseTypeCheckAnnotations(true).
+		WithDefaultRegoVersion(params.RegoVersion.ToAstRegoVersion())
+
+	runner := tester.NewRunner()
+
+	var tracer *cover.Cover
+	if params.Coverage {
+		tracer = cover.New()
+		runner = runner.SetCoverageQueryTracer(tracer)
+	}
+
+	ch, err := runner.
+		SetCompiler(compiler).
+		SetStore(store).
+		SetModules(modules).
+		SetDefaultRegoVersion(params.RegoVersion.ToAstRegoVersion()).
+		CapturePrintOutput(true).
+		Filter(params.Run).
+		RunTests(WithMocks(ctx, params.Mocks), txn)
+	if err != nil {
+		return nil, nil, undefinedFunctions(err, &BuildParams{Ignore: params.Ignore, RegoVersion: params.RegoVersion}, capabilities, paths)
+	}
+
+	var results []*tester.Result
+	for result := range ch {
+		results = append(results, result)
+	}
+
+	// tests run in parallel, results are reported in source order.
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Location.Compare(results[j].Location) < 0
+	})
+
+	if tracer == nil {
+		return results, nil, nil
+	}
+
+	return results, sourceCoverage(tracer, modules), nil
+}
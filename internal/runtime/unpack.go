@@ -0,0 +1,201 @@
+package runtime
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/pkg/errors"
+)
+
+// signaturesFile is the bundle entry holding the signatures, it is verified but not unpacked.
+const signaturesFile = "/.signatures.json"
+
+type UnpackParams struct {
+	// PubKey verifies the signatures of the bundle before anything is written, when set.
+	PubKey             string
+	PubKeyID           string
+	Algorithm          string
+	Scope              string
+	ExcludeVerifyFiles []string
+}
+
+// Unpack writes the files of a bundle tarball, its modules, data and manifest, to dir which must be missing or empty.
+// The tarball is read from open, once to verify the signatures when a key is given and once to stream its entries to
+// their files. Entries are checked as they are read: paths leaving dir and entries other than regular files are
+// refused. The files are written to a directory next to dir, moved to it once the whole tarball was read, so that
+// nothing is left when an entry is refused or the tarball fails to read. The signatures are left out.
+// It returns the paths of the written files, relative to dir.
+func Unpack(open func() (io.ReadCloser, error), dir string, params *UnpackParams) ([]string, error) {
+	if params.PubKey != "" {
+		if err := verifyBundle(open, params); err != nil {
+			return nil, err
+		}
+	}
+
+	exists, err := emptyDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0o750); err != nil {
+		return nil, err
+	}
+
+	staging, err := os.MkdirTemp(filepath.Dir(dir), "."+filepath.Base(dir)+"-*")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(staging)
+
+	names, err := extractBundle(open, staging)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(staging, 0o750); err != nil {
+		return nil, err
+	}
+
+	if exists {
+		if err := os.Remove(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.Rename(staging, dir); err != nil {
+		return nil, errors.Wrapf(err, "failed to write [%s]", dir)
+	}
+
+	return names, nil
+}
+
+func verifyBundle(open func() (io.ReadCloser, error), params *UnpackParams) error {
+	bvc, err := buildVerificationConfig(params.PubKey, params.PubKeyID, params.Algorithm, params.Scope, params.ExcludeVerifyFiles)
+	if err != nil {
+		return err
+	}
+
+	r, err := open()
+	if err != nil {
+		return err
+	}
+
+	defer r.Close()
+
+	_, err = bundle.NewCustomReader(bundle.NewTarballLoaderWithBaseURL(r, "")).
+		WithBundleVerificationConfig(bvc).
+		Read()
+	if err != nil {
+		return errors.Wrap(err, "bundle verification failed")
+	}
+
+	return nil
+}
+
+// extractBundle copies the regular files of a bundle tarball to dir, by their path relative to the bundle root.
+// It returns the sorted paths of the files.
+func extractBundle(open func() (io.ReadCloser, error), dir string) ([]string, error) {
+	r, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid bundle tarball")
+	}
+
+	tr := tar.NewReader(gz)
+	files := map[string]bool{}
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid bundle tarball")
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			continue
+		case tar.TypeReg:
+		default:
+			return nil, errors.Errorf("bundle entry [%s] is not a regular file", header.Name)
+		}
+
+		// bundle paths are rooted at the bundle, as in '/authz/policy.rego'.
+		name := path.Clean(strings.TrimPrefix(header.Name, "/"))
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return nil, errors.Errorf("bundle entry [%s] is outside of the bundle", header.Name)
+		}
+
+		if "/"+name == signaturesFile {
+			continue
+		}
+
+		if err := extractFile(tr, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			return nil, err
+		}
+
+		files[name] = true
+	}
+
+	// the rest of the stream is read for the reader to check what it read, as its digest.
+	if _, err := io.Copy(io.Discard, gz); err != nil {
+		return nil, errors.Wrap(err, "invalid bundle tarball")
+	}
+
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return nil, errors.Wrap(err, "invalid bundle tarball")
+	}
+
+	return slices.Sorted(maps.Keys(files)), nil
+}
+
+func extractFile(r io.Reader, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write [%s]", target)
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return errors.Wrapf(err, "failed to write [%s]", target)
+	}
+
+	return f.Close()
+}
+
+// emptyDir checks that dir is missing or an empty directory, it returns whether it exists.
+func emptyDir(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+
+	switch {
+	case os.IsNotExist(err):
+		return false, nil
+	case err != nil:
+		return false, err
+	case len(entries) > 0:
+		return false, errors.Errorf("directory [%s] is not empty", dir)
+	default:
+		return true, nil
+	}
+}
@@ -0,0 +1,135 @@
+package runtime_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+
+	"github.com/opcr-io/policy/internal/runtime"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	content  string
+}
+
+// tarball returns the opener of a bundle tarball holding entries.
+func tarball(t *testing.T, entries ...tarEntry) func() (io.ReadCloser, error) {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+
+	for _, e := range entries {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Mode:     0o600,
+			Size:     int64(len(e.content)),
+			Linkname: "/etc/passwd",
+		}))
+
+		_, err := tw.Write([]byte(e.content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	}
+}
+
+func TestUnpack(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+
+	files, err := runtime.Unpack(tarball(t,
+		tarEntry{name: "/.manifest", typeflag: tar.TypeReg, content: `{"roots":["authz"]}`},
+		tarEntry{name: "/authz/policy.rego", typeflag: tar.TypeReg, content: "package authz\n"},
+		tarEntry{name: "/.signatures.json", typeflag: tar.TypeReg, content: `{"signatures":[]}`},
+	), dir, &runtime.UnpackParams{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{".manifest", "authz/policy.rego"}, files)
+
+	buf, err := os.ReadFile(filepath.Join(dir, "authz", "policy.rego"))
+	require.NoError(t, err)
+	assert.Equal(t, "package authz\n", string(buf))
+
+	_, err = runtime.Unpack(tarball(t), dir, &runtime.UnpackParams{})
+	assert.ErrorContains(t, err, "is not empty")
+}
+
+func TestUnpackRefusesUnsafeEntries(t *testing.T) {
+	tcs := []struct {
+		name  string
+		entry tarEntry
+		err   string
+	}{
+		{"parent", tarEntry{name: "../escape.rego", typeflag: tar.TypeReg}, "outside of the bundle"},
+		{"nested parent", tarEntry{name: "/authz/../../escape.rego", typeflag: tar.TypeReg}, "outside of the bundle"},
+		{"symlink", tarEntry{name: "/authz/link", typeflag: tar.TypeSymlink}, "not a regular file"},
+		{"hard link", tarEntry{name: "/authz/link", typeflag: tar.TypeLink}, "not a regular file"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			dir := filepath.Join(root, "out")
+
+			_, err := runtime.Unpack(tarball(t,
+				tarEntry{name: "/authz/policy.rego", typeflag: tar.TypeReg, content: "package authz\n"},
+				tc.entry,
+			), dir, &runtime.UnpackParams{})
+			require.ErrorContains(t, err, tc.err)
+
+			// nothing is written when an entry is refused.
+			_, err = os.Stat(dir)
+			assert.True(t, os.IsNotExist(err))
+			_, err = os.Stat(filepath.Join(root, "escape.rego"))
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestUnpackLeavesNothingOnReadErrors(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "out")
+	require.NoError(t, os.Mkdir(dir, 0o750))
+
+	open := tarball(t, tarEntry{name: "/authz/policy.rego", typeflag: tar.TypeReg, content: "package authz\n"})
+
+	// the reader fails once the whole tarball was read, as a blob not matching its digest.
+	_, err := runtime.Unpack(func() (io.ReadCloser, error) {
+		r, err := open()
+		if err != nil {
+			return nil, err
+		}
+
+		return io.NopCloser(io.MultiReader(r, iotest.ErrReader(errors.New("mismatched digest")))), nil
+	}, dir, &runtime.UnpackParams{})
+	require.ErrorContains(t, err, "mismatched digest")
+
+	entries, err := os.ReadDir(root)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "the files written are removed")
+
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// an empty directory receives the files.
+	files, err := runtime.Unpack(open, dir, &runtime.UnpackParams{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"authz/policy.rego"}, files)
+}
@@ -0,0 +1,140 @@
+package runtime
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Watch calls onChange whenever a file below paths changes, until ctx is done.
+// Files excluded by the ignore patterns of a build are not watched, and bursts of changes within debounce
+// result in a single call.
+func Watch(ctx context.Context, logger *zerolog.Logger, paths, ignore []string, debounce time.Duration, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, "failed to create file watcher")
+	}
+	defer watcher.Close()
+
+	filter := buildCommandLoaderFilter(true, ignore)
+
+	// roots are kept to compute the depth of the changed files, as the loader does.
+	roots := map[string]string{}
+
+	for _, root := range paths {
+		if err := watchTree(watcher, filter, root, root, roots); err != nil {
+			return err
+		}
+	}
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			info, statErr := os.Lstat(event.Name)
+
+			root := rootOf(roots, event.Name)
+			if statErr == nil && filter(event.Name, info, depth(root, event.Name)) {
+				continue
+			}
+
+			// removed and renamed files are matched by their name only.
+			if statErr != nil && ignoredName(ignore, event.Name) {
+				continue
+			}
+
+			// new directories are not watched yet.
+			if event.Has(fsnotify.Create) && statErr == nil && info.IsDir() {
+				if err := watchTree(watcher, filter, root, event.Name, roots); err != nil {
+					logger.Warn().Err(err).Str("path", event.Name).Msg("Failed to watch new directory.")
+				}
+			}
+
+			if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+				continue
+			}
+
+			logger.Debug().Str("path", event.Name).Str("op", event.Op.String()).Msg("Source changed.")
+			timer.Reset(debounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			logger.Warn().Err(err).Msg("File watcher error.")
+
+		case <-timer.C:
+			onChange()
+		}
+	}
+}
+
+// ignoredName reports whether the base name of path matches an ignore pattern, as the loader filter does.
+func ignoredName(ignore []string, path string) bool {
+	for _, pattern := range ignore {
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// watchTree watches dir and the directories below it that are not excluded by filter.
+func watchTree(watcher *fsnotify.Watcher, filter func(string, os.FileInfo, int) bool, root, dir string, roots map[string]string) error {
+	return filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if filter(path, info, depth(root, path)) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		// a file given as a path is watched directly, the others through their directory.
+		if !info.IsDir() && path != root {
+			return nil
+		}
+
+		if err := watcher.Add(path); err != nil {
+			return errors.Wrapf(err, "failed to watch [%s]", path)
+		}
+
+		roots[path] = root
+
+		return nil
+	})
+}
+
+// rootOf returns the build path that contains path.
+func rootOf(roots map[string]string, path string) string {
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if root, ok := roots[dir]; ok {
+			return root
+		}
+
+		if parent := filepath.Dir(dir); parent == dir {
+			return path
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package runtime_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opcr-io/policy/internal/runtime"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const watchDebounce = 50 * time.Millisecond
+
+// watch runs runtime.Watch on dir in the background, each call of onChange is sent on the returned channel.
+func watch(t *testing.T, dir string, ignore []string) <-chan struct{} {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan struct{}, 10)
+	done := make(chan error)
+	logger := zerolog.Nop()
+
+	go func() {
+		done <- runtime.Watch(ctx, &logger, []string{dir}, ignore, watchDebounce, func() {
+			changes <- struct{}{}
+		})
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	// the watcher is set up asynchronously, changes made before it are not reported.
+	time.Sleep(100 * time.Millisecond)
+
+	return changes
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func changed(changes <-chan struct{}) bool {
+	select {
+	case <-changes:
+		return true
+	case <-time.After(10 * watchDebounce):
+		return false
+	}
+}
+
+func TestWatchDebouncesChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "policy.rego"), "package authz\n")
+
+	changes := watch(t, dir, nil)
+
+	for i := range 5 {
+		writeFile(t, filepath.Join(dir, "policy.rego"), fmt.Sprintf("package authz\n\nx := %d\n", i))
+	}
+
+	assert.True(t, changed(changes), "a burst of changes triggers a rebuild")
+	assert.False(t, changed(changes), "a burst of changes triggers a single rebuild")
+}
+
+func TestWatchSkipsIgnoredFiles(t *testing.T) {
+	dir := t.TempDir()
+	changes := watch(t, dir, []string{"*.txt"})
+
+	writeFile(t, filepath.Join(dir, "notes.txt"), "ignored")
+	assert.False(t, changed(changes), "ignored files do not trigger a rebuild")
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "notes.txt")))
+	assert.False(t, changed(changes), "removed ignored files do not trigger a rebuild")
+
+	writeFile(t, filepath.Join(dir, "policy.rego"), "package authz\n")
+	assert.True(t, changed(changes), "sources trigger a rebuild")
+}
+
+func TestWatchNewDirectories(t *testing.T) {
+	dir := t.TempDir()
+	changes := watch(t, dir, nil)
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "lib"), 0o700))
+	assert.True(t, changed(changes), "a new directory triggers a rebuild")
+
+	writeFile(t, filepath.Join(dir, "lib", "helpers.rego"), "package lib\n")
+	assert.True(t, changed(changes), "sources of new directories trigger a rebuild")
+}
@@ -3,19 +3,29 @@ package app
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/opcr-io/policy/internal/oci"
 	"github.com/opcr-io/policy/internal/parser"
 	"github.com/opcr-io/policy/internal/runtime"
+	perr "github.com/opcr-io/policy/pkg/errors"
 
 	"github.com/containerd/errdefs"
 	"github.com/distribution/reference"
+	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/opencontainers/go-digest"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
 	orasoci "oras.land/oras-go/v2/content/oci"
 
 	"github.com/pkg/errors"
@@ -25,141 +35,583 @@ import (
 const (
 	AnnotationPolicyRegistryType = "org.openpolicyregistry.type"
 	PolicyTypePolicy             = "policy"
+	// AnnotationEntrypoints records the entrypoints of the bundle, given and annotated, as a JSON list of slash
+	// separated paths.
+	AnnotationEntrypoints = "org.openpolicyregistry.entrypoints"
 )
 
-//nolint:funlen
-func (c *PolicyApp) Build(
-	ref string,
-	path []string,
-	annotations map[string]string,
-	runConfigFile string,
-	target string,
-	optimizationLevel int,
-	entrypoints []string,
-	revision string,
-	ignore []string,
-	capabilities string,
-	verificationKey string,
-	verificationKeyID string,
-	algorithm string,
-	scope string,
-	excludeVerifyFiles []string,
-	signingKey string,
-	claimsFile string,
-	regoVersion runtime.RegoVersion,
-) error {
+// BuildOptions describes a policy image build, it mirrors the compiler parameters of the OPA runtime.
+type BuildOptions struct {
+	// Tags are the references given to the image, "default" when empty.
+	Tags        []string
+	Paths       []string
+	Annotations map[string]string
+	// Push uploads the image and all of its tags to their registries once it is built.
+	Push bool
+	// NoCache compiles the sources even when an image was built before from the same inputs.
+	NoCache bool
+	// Created is recorded as the creation time of the image, the current time when zero.
+	// Identical sources built with the same creation time produce the same image digest.
+	Created time.Time
+	// Lint applies the lint rules, except LintDisable, to the sources and fails the build on violations.
+	Lint        bool
+	LintDisable []string
+	// Test runs the Rego tests of the sources and fails the build when any fails, or when the coverage of
+	// the modules, in percent, is below MinCoverage.
+	Test        bool
+	MinCoverage float64
+	// Schema is a JSON schema file for the input, or a directory of schemas, the modules are type-checked against.
+	// The schemas are stored with the image.
+	Schema string
+	// Docs records the documentation summary of the packages, their titles and entrypoints, as an image annotation.
+	Docs bool
+	// CapabilityProfile is the capability profile of the config the sources are compiled with, by default the one
+	// required for the tags. It is recorded as an image annotation.
+	CapabilityProfile string
+	// OPAVersions are checked for compatibility with the sources, the lowest compatible one is recorded as an
+	// image annotation. The build fails when none is compatible.
+	OPAVersions []string
+
+	CapabilitiesJSONFile string
+	OptimizationLevel    int
+	Entrypoints          []string
+	Revision             string
+	Ignore               []string
+	Algorithm            string
+	Key                  string
+	Scope                string
+	PubKey               string
+	PubKeyID             string
+	ClaimsFile           string
+	ExcludeVerifyFiles   []string
+	RegoVersion          RegoVersion
+}
+
+// BuildResult describes the image created by a build.
+type BuildResult struct {
+	Digest      string   `json:"digest"`
+	LayerDigest string   `json:"layer_digest"`
+	Size        int64    `json:"size"`
+	Tags        []string `json:"tags"`
+	Pushed      []string `json:"pushed,omitempty"`
+	Cached      bool     `json:"cached"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+func (c *PolicyApp) Build(opts *BuildOptions) error {
 	defer c.Cancel()
 
-	workDir, err := os.MkdirTemp("", "policy-build")
+	result, err := c.client().Build(c.Context, opts)
 	if err != nil {
-		return errors.Wrap(err, "failed to create temporary build directory")
+		return err
 	}
 
-	defer func() {
-		err := os.RemoveAll(workDir)
+	c.reportBuild(result)
+
+	return c.Output.Print(result)
+}
+
+func (c *PolicyApp) reportBuild(result *BuildResult) {
+	for _, warning := range result.Warnings {
+		c.UI.Exclamation().Msg(warning)
+	}
+
+	if result.Cached {
+		c.UI.Normal().
+			WithStringValue("digest", result.Digest).
+			Msg("Using cached image.")
+	} else {
+		c.UI.Normal().
+			WithStringValue("digest", result.Digest).
+			Msg("Created new image.")
+	}
+
+	for _, tag := range result.Tags {
+		c.UI.Normal().WithStringValue("reference", tag).Msg("Tagging image.")
+	}
+
+	for _, ref := range result.Pushed {
+		c.UI.Normal().
+			WithStringValue("digest", result.Digest).
+			Msgf("Pushed ref [%s].", ref)
+	}
+}
+
+// Build compiles the policy sources into a bundle and stores it as a tagged image in the local store.
+// Unless disabled, an image built before from the same inputs is reused instead of compiling the sources again.
+func (c *Client) Build(ctx context.Context, opts *BuildOptions) (*BuildResult, error) {
+	if err := checkAnnotations(opts.Annotations); err != nil {
+		return nil, err
+	}
+
+	parsedRefs, err := c.parseTags(opts.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := c.buildProfile(opts, parsedRefs)
+	if err != nil {
+		return nil, err
+	}
+
+	if profile != nil {
+		opts = opts.withAnnotation(AnnotationCapabilityProfile, profile.Name)
+	}
+
+	if opts.Lint {
+		report, err := c.Lint(&LintOptions{
+			Paths:                opts.Paths,
+			Ignore:               opts.Ignore,
+			CapabilitiesJSONFile: opts.CapabilitiesJSONFile,
+			RegoVersion:          opts.RegoVersion,
+			Disable:              opts.LintDisable,
+		})
 		if err != nil {
-			c.UI.Problem().WithErr(err).Msg("Failed to remove temporary working directory.")
+			return nil, err
 		}
-	}()
 
-	opaRuntime, err := runtime.New(c.Logger.WithContext(c.Context))
+		if len(report.Violations) > 0 {
+			return nil, &LintViolationsError{Violations: report.Violations}
+		}
+	}
+
+	if opts.Test || opts.MinCoverage > 0 {
+		if err := c.buildTests(ctx, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Docs {
+		summary, err := docsAnnotation(opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to document policy sources")
+		}
+
+		// the summary is an annotation like any other, it is part of the cache key.
+		opts = opts.withAnnotation(AnnotationDocs, summary)
+	}
+
+	if len(opts.OPAVersions) > 0 {
+		minVersion, err := c.minOPAVersion(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = opts.withAnnotation(AnnotationMinOPAVersion, minVersion)
+	}
+
+	entrypoints, err := buildEntrypoints(opts)
 	if err != nil {
-		return errors.Wrap(err, "failed to setup the OPA runtime")
+		return nil, classifyBuildError(err, false)
 	}
 
-	opaRuntime.Config.InstanceID = "policy-build"
+	schemas, err := loadSchemas(opts.Schema)
+	if err != nil {
+		return nil, err
+	}
 
-	outFile := filepath.Join(workDir, "bundle.tgz")
+	ociStore, err := orasoci.New(c.config.PoliciesRoot())
+	if err != nil {
+		return nil, err
+	}
 
-	err = opaRuntime.Build(&runtime.BuildParams{
-		CapabilitiesJSONFile: capabilities,
+	params := &runtime.BuildParams{
+		CapabilitiesJSONFile: opts.CapabilitiesJSONFile,
 		Target:               runtime.Rego,
-		OptimizationLevel:    optimizationLevel,
+		OptimizationLevel:    opts.OptimizationLevel,
 		Entrypoints:          entrypoints,
-		OutputFile:           outFile,
-		Revision:             revision,
-		Ignore:               ignore,
-		Debug:                c.Logger.GetLevel() == zerolog.DebugLevel,
-		Algorithm:            algorithm,
-		Key:                  signingKey,
-		Scope:                scope,
-		ClaimsFile:           claimsFile,
-		PubKey:               verificationKey,
-		PubKeyID:             verificationKeyID,
-		ExcludeVerifyFiles:   excludeVerifyFiles,
-		RegoVersion:          regoVersion,
-	}, path)
+		Revision:             opts.Revision,
+		Ignore:               opts.Ignore,
+		Debug:                c.logger.GetLevel() == zerolog.DebugLevel,
+		Algorithm:            opts.Algorithm,
+		Key:                  opts.Key,
+		Scope:                opts.Scope,
+		ClaimsFile:           opts.ClaimsFile,
+		PubKey:               opts.PubKey,
+		PubKeyID:             opts.PubKeyID,
+		ExcludeVerifyFiles:   opts.ExcludeVerifyFiles,
+		RegoVersion:          opts.RegoVersion,
+		Schemas:              schemas,
+	}
+
+	if profile != nil {
+		params.Capabilities = profile.Capabilities
+	}
+
+	// the image is titled after its first tag.
+	title := parsedRefs[0]
+
+	var cache *buildCache
+	if !opts.NoCache {
+		cache = c.buildCache(params, opts, title)
+	}
+
+	entry := cache.lookup(ctx, ociStore)
+	cached := entry != nil
+
+	if !cached {
+		entry, err = c.buildImage(ctx, ociStore, params, opts, title, profile)
+		if err != nil {
+			return nil, err
+		}
+
+		cache.store(entry)
+	}
+
+	result := &BuildResult{
+		Digest:      entry.Manifest.Digest.String(),
+		LayerDigest: entry.Layer.Digest.String(),
+		Size:        entry.Layer.Size,
+		Cached:      cached,
+		Warnings:    entry.Warnings,
+	}
+
+	for _, parsedRef := range parsedRefs {
+		if err := ociStore.Tag(ctx, entry.Manifest, parsedRef.String()); err != nil {
+			return nil, err
+		}
+
+		result.Tags = append(result.Tags, parsedRef.String())
+	}
+
+	err = ociStore.SaveIndex()
 	if err != nil {
-		return errors.Wrap(err, "failed to build opa policy bundle")
+		return nil, err
+	}
+
+	if !opts.Push {
+		return result, nil
 	}
 
-	ociStore, err := orasoci.New(c.Configuration.PoliciesRoot())
+	// blobs uploaded for the first tag are found by the existence checks of the following ones.
+	for _, tag := range result.Tags {
+		pushed, err := c.Push(ctx, &PushOptions{Reference: tag})
+		if err != nil {
+			return nil, err
+		}
+
+		result.Pushed = append(result.Pushed, pushed.Reference)
+	}
+
+	return result, nil
+}
+
+// buildTests runs the Rego tests of the sources of a build, the image is not created when they fail.
+func (c *Client) buildTests(ctx context.Context, opts *BuildOptions) error {
+	results, coverage, err := c.Test(ctx, &TestOptions{
+		Paths:                opts.Paths,
+		Ignore:               testIgnore(opts.Paths, opts.Ignore),
+		CapabilitiesJSONFile: opts.CapabilitiesJSONFile,
+		RegoVersion:          opts.RegoVersion,
+		Schema:               opts.Schema,
+		MinCoverage:          opts.MinCoverage,
+	})
 	if err != nil {
 		return err
 	}
 
-	if ref == "" {
-		ref = "default"
+	report := testReport(results)
+	report.Coverage = coverage
+
+	if err := report.check(opts.MinCoverage); err != nil {
+		failed := []string{}
+
+		for _, tc := range report.Tests {
+			if tc.Outcome == "fail" || tc.Outcome == "error" {
+				failed = append(failed, tc.Package+"."+tc.Name)
+			}
+		}
+
+		if len(failed) > 0 {
+			return errors.Wrapf(err, "failed tests: %s", strings.Join(failed, ", "))
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// testIgnore returns the ignore patterns of a build without those leaving only the tests of the sources out of the
+// bundle, as '*_test.rego'. Patterns matching other Rego files, as '*.rego', are kept.
+func testIgnore(paths, ignore []string) []string {
+	var tests, sources []string
+
+	for _, path := range paths {
+		_ = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || filepath.Ext(file) != ".rego" {
+				return nil //nolint:nilerr // unreadable paths are reported by the tests.
+			}
+
+			if strings.HasSuffix(file, "_test.rego") {
+				tests = append(tests, d.Name())
+			} else {
+				sources = append(sources, d.Name())
+			}
+
+			return nil
+		})
+	}
+
+	matches := func(pattern string, names []string) bool {
+		return slices.ContainsFunc(names, func(name string) bool {
+			ok, _ := filepath.Match(pattern, name)
+			return ok
+		})
+	}
+
+	return slices.DeleteFunc(slices.Clone(ignore), func(pattern string) bool {
+		return matches(pattern, tests) && !matches(pattern, sources)
+	})
+}
+
+// reservedAnnotations are recorded by the build from what it checked, they cannot be given as annotations.
+var reservedAnnotations = []string{
+	AnnotationEntrypoints,
+	AnnotationCapabilityProfile,
+	AnnotationDocs,
+	AnnotationMinOPAVersion,
+}
+
+func checkAnnotations(annotations map[string]string) error {
+	for _, key := range reservedAnnotations {
+		if _, ok := annotations[key]; ok {
+			return errors.Errorf("annotation [%s] is reserved, it is recorded by the build", key)
+		}
+	}
+
+	return nil
+}
+
+// withAnnotation returns a copy of the options with an annotation added, annotations are part of the cache key.
+func (o *BuildOptions) withAnnotation(key, value string) *BuildOptions {
+	annotated := *o
+	annotated.Annotations = maps.Clone(o.Annotations)
+
+	if annotated.Annotations == nil {
+		annotated.Annotations = map[string]string{}
+	}
+
+	annotated.Annotations[key] = value
+
+	return &annotated
+}
+
+func (c *Client) parseTags(tags []string) ([]reference.Named, error) {
+	if len(tags) == 0 {
+		tags = []string{"default"}
+	}
+
+	parsedRefs := make([]reference.Named, 0, len(tags))
+
+	for _, tag := range tags {
+		parsedRef, err := parser.CalculateNamedRef(tag, c.config.DefaultDomain)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to calculate policy reference")
+		}
+
+		parsedRefs = append(parsedRefs, parsedRef)
 	}
 
-	parsedRef, err := parser.CalculateNamedRef(ref, c.Configuration.DefaultDomain)
+	return parsedRefs, nil
+}
+
+// buildImage compiles the bundle and stores it as a new image, it returns the descriptors of the image.
+func (c *Client) buildImage(
+	ctx context.Context,
+	ociStore *orasoci.Store,
+	params *runtime.BuildParams,
+	opts *BuildOptions,
+	title reference.Named,
+	profile *capabilityProfile,
+) (*buildCacheEntry, error) {
+	workDir, err := os.MkdirTemp("", "policy-build")
 	if err != nil {
-		return errors.Wrap(err, "failed to calculate policy reference")
+		return nil, errors.Wrap(err, "failed to create temporary build directory")
 	}
 
-	annotations = buildAnnotations(annotations, parsedRef, regoVersion)
+	defer func() {
+		err := os.RemoveAll(workDir)
+		if err != nil {
+			c.logger.Warn().Err(err).Msg("Failed to remove temporary working directory.")
+		}
+	}()
 
-	desc, err := c.createImage(ociStore, outFile, annotations)
+	opaRuntime, err := runtime.New(c.logger.WithContext(ctx))
 	if err != nil {
-		return err
+		return nil, errors.Wrap(err, "failed to setup the OPA runtime")
 	}
 
-	err = ociStore.Tag(c.Context, desc, parsedRef.String())
+	opaRuntime.Config.InstanceID = "policy-build"
+
+	outFile := filepath.Join(workDir, "bundle.tgz")
+
+	bundleFile, err := os.Create(outFile)
 	if err != nil {
-		return err
+		return nil, errors.Wrap(err, "failed to create bundle file")
 	}
 
-	c.UI.Normal().WithStringValue("reference", parsedRef.String()).Msg("Tagging image.")
+	defer bundleFile.Close()
 
-	err = ociStore.SaveIndex()
+	// the bundle digest is computed while the compiler streams the bundle to disk.
+	digester := digest.Canonical.Digester()
+
+	params.OutputFile = outFile
+	params.Output = io.MultiWriter(bundleFile, digester.Hash())
+
+	warnings, err := opaRuntime.Build(params, opts.Paths)
 	if err != nil {
-		return err
+		return nil, classifyBuildError(profile.explain(err), opts.PubKey != "")
 	}
 
-	return nil
+	if err := bundleFile.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to close bundle file")
+	}
+
+	created := opts.Created
+	if created.IsZero() {
+		created = time.Now()
+	}
+
+	annotations, err := buildAnnotations(maps.Clone(opts.Annotations), title, opts.RegoVersion, created, params.Entrypoints)
+	if err != nil {
+		return nil, err
+	}
+
+	desc, layerDesc, err := c.createImage(ctx, ociStore, outFile, digester.Digest(), annotations, params.Schemas)
+	if err != nil {
+		return nil, err
+	}
+
+	return &buildCacheEntry{Manifest: desc, Layer: layerDesc, Warnings: warnings}, nil
+}
+
+// verificationErrorMarkers identify the bundle signature verification failures reported by OPA.
+var verificationErrorMarkers = []string{"signature", "digest mismatch", "scope mismatch", "verification"}
+
+// classifyBuildError tags compile errors and, when a verification key is used, signature verification errors.
+func classifyBuildError(err error, verifying bool) error {
+	var astErrs ast.Errors
+	if errors.As(err, &astErrs) {
+		return perr.ErrCompileFailed.WithError(err)
+	}
+
+	if verifying {
+		for _, marker := range verificationErrorMarkers {
+			if strings.Contains(err.Error(), marker) {
+				return perr.ErrVerificationFailed.WithError(err)
+			}
+		}
+	}
+
+	return errors.Wrap(err, "failed to build opa policy bundle")
 }
 
-func buildAnnotations(annotations map[string]string, parsedRef reference.Named, regoVersion runtime.RegoVersion) map[string]string {
+func buildAnnotations(
+	annotations map[string]string,
+	parsedRef reference.Named,
+	regoVersion runtime.RegoVersion,
+	created time.Time,
+	entrypoints []string,
+) (map[string]string, error) {
 	if annotations == nil {
 		annotations = map[string]string{}
 	}
 
 	annotations[v1.AnnotationTitle] = parsedRef.Name()
 	annotations[AnnotationPolicyRegistryType] = PolicyTypePolicy
-	annotations[v1.AnnotationCreated] = time.Now().UTC().Format(time.RFC3339)
+	annotations[v1.AnnotationCreated] = created.UTC().Format(time.RFC3339)
 	annotations["rego.version"] = regoVersion.String()
 
-	return annotations
+	if len(entrypoints) > 0 {
+		buf, err := json.Marshal(entrypoints)
+		if err != nil {
+			return nil, err
+		}
+
+		annotations[AnnotationEntrypoints] = string(buf)
+	}
+
+	return annotations, nil
+}
+
+// loadSchemas reads the schemas of a build, test or evaluation, nil without a schema path.
+func loadSchemas(path string) (runtime.Schemas, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	return runtime.LoadSchemas(path)
+}
+
+// imageSchemas returns the schemas stored in the config of an image, nil for images built without.
+func (c *Client) imageSchemas(ctx context.Context, ociClient *oci.Oci, descriptor v1.Descriptor) (runtime.Schemas, error) {
+	if descriptor.MediaType != v1.MediaTypeImageManifest {
+		return nil, nil
+	}
+
+	manifest, err := ociClient.GetManifest(&descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.Config.MediaType != v1.MediaTypeImageConfig {
+		return nil, nil
+	}
+
+	buf, err := content.FetchAll(ctx, ociClient.GetStore(), manifest.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &policyConfig{}
+	if err := json.Unmarshal(buf, cfg); err != nil {
+		return nil, errors.Wrap(err, "invalid image config")
+	}
+
+	return cfg.Schemas, nil
+}
+
+// buildEntrypoints returns the entrypoints given to the build followed by those of the METADATA annotations.
+func buildEntrypoints(opts *BuildOptions) ([]string, error) {
+	modules, err := runtime.LoadAnnotatedModules(opts.Paths, opts.Ignore, opts.RegoVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	annotated, err := runtime.AnnotatedEntrypoints(modules)
+	if err != nil {
+		return nil, err
+	}
+
+	return runtime.MergeEntrypoints(opts.Entrypoints, annotated), nil
 }
 
-func (c *PolicyApp) createImage(ociStore *orasoci.Store, tarball string, annotations map[string]string) (v1.Descriptor, error) {
+func (c *Client) createImage(
+	ctx context.Context,
+	ociStore *orasoci.Store,
+	tarball string,
+	tarDigest digest.Digest,
+	annotations map[string]string,
+	schemas runtime.Schemas,
+) (manifestDesc, layerDesc v1.Descriptor, err error) {
 	ociStore.AutoSaveIndex = true
 	ociStore.AutoGC = true
 
 	// tarball layer
-	tarDescriptor, err := c.createTarLayer(ociStore, tarball, annotations)
+	tarDescriptor, err := c.createTarLayer(ctx, ociStore, tarball, tarDigest, annotations)
 	if err != nil {
-		return v1.Descriptor{}, err
+		return v1.Descriptor{}, v1.Descriptor{}, err
 	}
 
 	// cfg layer
-	cfgDescriptor, err := c.createEmptyCfgLayer(ociStore)
+	cfgDescriptor, err := c.createCfgLayer(ctx, ociStore, annotations[v1.AnnotationCreated], schemas)
 	if err != nil {
-		return v1.Descriptor{}, err
+		return v1.Descriptor{}, v1.Descriptor{}, err
 	}
 
-	manifestDesc, err := oras.PackManifest(
-		c.Context,
+	manifestDesc, err = oras.PackManifest(
+		ctx,
 		ociStore,
 		oras.PackManifestVersion1_1,
 		v1.MediaTypeImageManifest,
@@ -170,51 +622,70 @@ func (c *PolicyApp) createImage(ociStore *orasoci.Store, tarball string, annotat
 		},
 	)
 	if err != nil {
-		return v1.Descriptor{}, err
+		return v1.Descriptor{}, v1.Descriptor{}, err
 	}
 
-	c.UI.Normal().
-		WithStringValue("digest", manifestDesc.Digest.String()).
-		Msg("Created new image.")
+	return manifestDesc, tarDescriptor, nil
+}
 
-	return manifestDesc, nil
+// policyConfig is the config of an image, empty unless the build was given schemas.
+type policyConfig struct {
+	// Schemas are the JSON schemas the modules were type-checked against, by reference.
+	Schemas runtime.Schemas `json:"schemas,omitempty"`
 }
 
-func (c *PolicyApp) createEmptyCfgLayer(ociStore *orasoci.Store) (v1.Descriptor, error) {
+func (c *Client) createCfgLayer(
+	ctx context.Context,
+	ociStore *orasoci.Store,
+	created string,
+	schemas runtime.Schemas,
+) (v1.Descriptor, error) {
 	cfg := []byte("{}")
+	mediaType := v1.MediaTypeEmptyJSON
+
+	if len(schemas) > 0 {
+		buf, err := json.Marshal(&policyConfig{Schemas: schemas})
+		if err != nil {
+			return v1.Descriptor{}, err
+		}
+
+		cfg = buf
+		mediaType = v1.MediaTypeImageConfig
+	}
 
 	cfgDescriptor := v1.Descriptor{
-		MediaType: v1.MediaTypeEmptyJSON,
+		MediaType: mediaType,
 		Digest:    digest.FromBytes(cfg),
 		Size:      int64(len(cfg)),
 	}
 
-	cfgExist, err := ociStore.Exists(c.Context, cfgDescriptor)
+	cfgExist, err := ociStore.Exists(ctx, cfgDescriptor)
 	if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
 		return v1.Descriptor{}, err
 	}
 
-	if err := ociStore.Delete(c.Context, cfgDescriptor); cfgExist && err != nil {
+	if err := ociStore.Delete(ctx, cfgDescriptor); cfgExist && err != nil {
 		return v1.Descriptor{}, err
 	}
 
-	if err := ociStore.Push(c.Context, cfgDescriptor, bytes.NewReader(cfg)); err != nil {
+	if err := ociStore.Push(ctx, cfgDescriptor, bytes.NewReader(cfg)); err != nil {
 		return v1.Descriptor{}, err
 	}
 
 	cfgDescriptor.Annotations = map[string]string{
-		v1.AnnotationCreated: time.Now().UTC().Format(time.RFC3339),
+		v1.AnnotationCreated: created,
 	}
 
 	return cfgDescriptor, nil
 }
 
-func (c *PolicyApp) createTarLayer(ociStore *orasoci.Store, tarball string, annotations map[string]string) (v1.Descriptor, error) {
-	tarDigest, err := c.fileDigest(tarball)
-	if err != nil {
-		return v1.Descriptor{}, err
-	}
-
+func (c *Client) createTarLayer(
+	ctx context.Context,
+	ociStore *orasoci.Store,
+	tarball string,
+	tarDigest digest.Digest,
+	annotations map[string]string,
+) (v1.Descriptor, error) {
 	tarReader, err := os.Open(tarball)
 	if err != nil {
 		return v1.Descriptor{}, err
@@ -222,7 +693,7 @@ func (c *PolicyApp) createTarLayer(ociStore *orasoci.Store, tarball string, anno
 
 	defer func() {
 		if err := tarReader.Close(); err != nil {
-			c.UI.Problem().WithErr(err).Msg("Failed to close bundle tarball.")
+			c.logger.Warn().Err(err).Msg("Failed to close bundle tarball.")
 		}
 	}()
 
@@ -238,42 +709,21 @@ func (c *PolicyApp) createTarLayer(ociStore *orasoci.Store, tarball string, anno
 		MediaType:   oci.MediaTypeImageLayer,
 	}
 
-	exists, err := ociStore.Exists(c.Context, tarDescriptor)
+	exists, err := ociStore.Exists(ctx, tarDescriptor)
 	if err != nil && !errors.Is(err, errdefs.ErrNotFound) {
 		return v1.Descriptor{}, err
 	}
 
 	// delete if exists
-	if err := ociStore.Delete(c.Context, tarDescriptor); exists && err != nil {
+	if err := ociStore.Delete(ctx, tarDescriptor); exists && err != nil {
 		return v1.Descriptor{}, err
 	}
 
 	reader := bufio.NewReader(tarReader)
 
-	if err := ociStore.Push(c.Context, tarDescriptor, reader); err != nil {
+	if err := ociStore.Push(ctx, tarDescriptor, reader); err != nil {
 		return v1.Descriptor{}, err
 	}
 
 	return tarDescriptor, nil
 }
-
-func (c *PolicyApp) fileDigest(file string) (digest.Digest, error) {
-	fd, err := os.Open(file)
-	if err != nil {
-		return "", err
-	}
-
-	defer func() {
-		err := fd.Close()
-		if err != nil {
-			c.UI.Problem().WithErr(err).Msg("Failed to close bundle tarball when calculating digest.")
-		}
-	}()
-
-	fDigest, err := digest.FromReader(fd)
-	if err != nil {
-		return "", err
-	}
-
-	return fDigest, nil
-}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/opcr-io/policy/internal/runtime"
+	"github.com/opcr-io/policy/pkg/x"
+	"github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/rs/zerolog"
+	orasoci "oras.land/oras-go/v2/content/oci"
+)
+
+// buildCacheEntry records the image built for a set of build inputs.
+type buildCacheEntry struct {
+	Manifest v1.Descriptor `json:"manifest"`
+	Layer    v1.Descriptor `json:"layer"`
+	Warnings []string      `json:"warnings,omitempty"`
+}
+
+// buildCache is the cache entry file of a build, a nil cache never hits and stores nothing.
+type buildCache struct {
+	path   string
+	logger *zerolog.Logger
+}
+
+// buildCache returns the cache of a build, keyed by its bundle inputs and by the inputs of the image manifest.
+// Caching is disabled when the inputs cannot be read, the build then reports the actual error.
+func (c *Client) buildCache(params *runtime.BuildParams, opts *BuildOptions, title reference.Named) *buildCache {
+	bundleKey, err := runtime.BuildKey(params, opts.Paths)
+	if err != nil {
+		c.logger.Debug().Err(err).Msg("Build cache disabled.")
+		return nil
+	}
+
+	imageKey := struct {
+		Bundle      digest.Digest
+		Title       string
+		Annotations map[string]string
+		Created     string
+	}{
+		Bundle:      bundleKey,
+		Title:       title.Name(),
+		Annotations: opts.Annotations,
+	}
+
+	// without an explicit creation time, a cached image keeps the time it was first built.
+	if !opts.Created.IsZero() {
+		imageKey.Created = opts.Created.UTC().Format(time.RFC3339)
+	}
+
+	buf, err := json.Marshal(imageKey)
+	if err != nil {
+		c.logger.Debug().Err(err).Msg("Build cache disabled.")
+		return nil
+	}
+
+	return &buildCache{
+		path:   filepath.Join(c.config.BuildCacheRoot(), digest.FromBytes(buf).Encoded()+".json"),
+		logger: c.logger,
+	}
+}
+
+// lookup returns the cached image when its manifest and layer are still in the local store.
+func (b *buildCache) lookup(ctx context.Context, ociStore *orasoci.Store) *buildCacheEntry {
+	if b == nil {
+		return nil
+	}
+
+	buf, err := os.ReadFile(b.path)
+	if err != nil {
+		return nil
+	}
+
+	entry := &buildCacheEntry{}
+	if err := json.Unmarshal(buf, entry); err != nil {
+		b.logger.Debug().Err(err).Str("path", b.path).Msg("Ignoring invalid build cache entry.")
+		return nil
+	}
+
+	for _, desc := range []v1.Descriptor{entry.Manifest, entry.Layer} {
+		if exists, err := ociStore.Exists(ctx, desc); err != nil || !exists {
+			return nil
+		}
+	}
+
+	return entry
+}
+
+// store records a built image, failing to do so only costs a rebuild next time.
+func (b *buildCache) store(entry *buildCacheEntry) {
+	if b == nil {
+		return
+	}
+
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		b.logger.Warn().Err(err).Msg("Failed to encode build cache entry.")
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(b.path), x.OwnerReadWriteExecute); err != nil {
+		b.logger.Warn().Err(err).Msg("Failed to create build cache directory.")
+		return
+	}
+
+	if err := os.WriteFile(b.path, buf, x.OwnerReadWrite); err != nil {
+		b.logger.Warn().Err(err).Msg("Failed to write build cache entry.")
+	}
+}
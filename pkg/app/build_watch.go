@@ -0,0 +1,43 @@
+package app
+
+import (
+	"time"
+
+	"github.com/opcr-io/policy/internal/runtime"
+	"github.com/pkg/errors"
+)
+
+// WatchDebounce is how long sources must stay unchanged before a rebuild starts.
+const WatchDebounce = 300 * time.Millisecond
+
+// BuildWatch builds the policy, then rebuilds and retags it whenever its sources change, until the context is canceled.
+// Failed builds are reported without stopping the watch, the tags keep pointing to the last successful build.
+func (c *PolicyApp) BuildWatch(opts *BuildOptions) error {
+	defer c.Cancel()
+
+	if opts.Push {
+		return errors.New("--watch cannot be combined with --push")
+	}
+
+	c.rebuild(opts)
+
+	c.UI.Note().Msgf("Watching %d path(s) for changes, press Ctrl+C to stop.", len(opts.Paths))
+
+	return runtime.Watch(c.Context, c.Logger, opts.Paths, opts.Ignore, WatchDebounce, func() {
+		c.rebuild(opts)
+	})
+}
+
+func (c *PolicyApp) rebuild(opts *BuildOptions) {
+	result, err := c.client().Build(c.Context, opts)
+	if err != nil {
+		c.UI.Problem().WithErr(err).Msg("Build failed.")
+		return
+	}
+
+	c.reportBuild(result)
+
+	if err := c.Output.Print(result); err != nil {
+		c.UI.Problem().WithErr(err).Msg("Failed to print build result.")
+	}
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opcr-io/policy/internal/runtime"
+	"github.com/opcr-io/policy/pkg/table"
+	"github.com/pkg/errors"
+)
+
+// BuiltinsScanOptions configures the scan of policy sources for functions that need stub builtins.
+type BuiltinsScanOptions struct {
+	Paths                []string
+	Ignore               []string
+	CapabilitiesJSONFile string
+	RegoVersion          RegoVersion
+	// Manifest is the manifest updated with the required builtins, the .manifest of the first path by default.
+	Manifest string
+	// DryRun reports the undefined functions without updating the manifest.
+	DryRun bool
+}
+
+// BuiltinsScan finds the functions called by the policy sources that are neither built-ins nor rules,
+// and declares them as required builtins in the manifest so that the build registers stubs for them.
+func (c *PolicyApp) BuiltinsScan(opts *BuiltinsScanOptions) error {
+	defer c.Cancel()
+
+	capabilities, err := runtime.LoadCapabilities(opts.CapabilitiesJSONFile)
+	if err != nil {
+		return err
+	}
+
+	functions, err := runtime.ScanFunctions(opts.Paths, opts.Ignore, capabilities, opts.RegoVersion)
+	if err != nil {
+		return errors.Wrap(err, "failed to scan policy sources")
+	}
+
+	result := &BuiltinsScan{Functions: functions}
+
+	manifest := opts.Manifest
+	if manifest == "" && len(opts.Paths) > 0 {
+		manifest = filepath.Join(opts.Paths[0], ".manifest")
+	}
+
+	if !opts.DryRun && manifest != "" && stubbable(functions) {
+		if _, err := runtime.UpdateManifestBuiltins(manifest, functions); err != nil {
+			return err
+		}
+
+		result.Manifest = manifest
+		result.Updated = true
+	}
+
+	if c.Output.Structured() {
+		return c.Output.Print(result)
+	}
+
+	if len(functions) == 0 {
+		c.UI.Normal().Msg("No undefined functions found.")
+		return nil
+	}
+
+	data := [][]any{}
+
+	for _, fn := range functions {
+		name := fn.Name
+		if fn.Rule {
+			name += " (rule)"
+		}
+
+		data = append(data, []any{name, fn.Arity, strings.Join(fn.Locations, ", ")})
+	}
+
+	t := table.New(os.Stdout)
+	t.Header("Name", "Arity", "Locations")
+	t.Bulk(data)
+	t.Render()
+
+	for _, fn := range functions {
+		if fn.Rule {
+			c.UI.Exclamation().Msgf("%s refers to an undefined rule and cannot be stubbed.", fn.Name)
+		}
+	}
+
+	if result.Updated {
+		c.UI.Normal().WithStringValue("manifest", manifest).Msg("Updated required builtins.")
+	}
+
+	return nil
+}
+
+func stubbable(functions []runtime.UndefinedFunction) bool {
+	for _, fn := range functions {
+		if !fn.Rule {
+			return true
+		}
+	}
+
+	return false
+}
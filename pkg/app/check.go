@@ -0,0 +1,180 @@
+package app
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/opcr-io/policy/internal/runtime"
+	perr "github.com/opcr-io/policy/pkg/errors"
+	"github.com/opcr-io/policy/pkg/table"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/semver"
+)
+
+// AnnotationMinOPAVersion records the lowest of the OPA versions checked by the build that can load the bundle.
+const AnnotationMinOPAVersion = "org.openpolicyregistry.min-opa-version"
+
+// CheckOptions describes a compatibility check of policy sources, or of a policy image when Paths is a single
+// reference that is not a path, against versions of OPA.
+type CheckOptions struct {
+	Paths       []string
+	Ignore      []string
+	RegoVersion RegoVersion
+	// OPAVersions are the versions checked, as '0.68', '1.4' or '1.4.2'.
+	OPAVersions []string
+}
+
+// CheckReport tells which of the checked OPA versions can load a policy.
+type CheckReport struct {
+	Versions []runtime.VersionCompatibility `json:"versions"`
+	// MinimumVersion is the lowest compatible version, empty when there is none.
+	MinimumVersion string `json:"minimum_version,omitempty"`
+}
+
+func (c *PolicyApp) Check(opts *CheckOptions) error {
+	defer c.Cancel()
+
+	report, err := c.client().Check(c.Context, opts)
+	if err != nil {
+		return err
+	}
+
+	if c.Output.Structured() {
+		if err := c.Output.Print(report); err != nil {
+			return err
+		}
+	} else {
+		data := [][]any{}
+		for _, v := range report.Versions {
+			compatible := "yes"
+			if !v.Compatible {
+				compatible = "no"
+			}
+
+			data = append(data, []any{v.Requested, v.Version, compatible, strings.Join(v.Errors, "\n")})
+		}
+
+		t := table.New(os.Stdout)
+		t.Header("Requested", "Version", "Compatible", "Errors")
+		t.Bulk(data)
+		t.Render()
+
+		if report.MinimumVersion != "" {
+			c.UI.Normal().
+				WithStringValue("version", report.MinimumVersion).
+				Msg("Minimum compatible OPA version.")
+		}
+	}
+
+	incompatible := []string{}
+
+	for _, v := range report.Versions {
+		if !v.Compatible {
+			incompatible = append(incompatible, v.Requested)
+		}
+	}
+
+	if len(incompatible) > 0 {
+		return errors.Errorf("policy cannot be loaded by OPA %s", strings.Join(incompatible, ", "))
+	}
+
+	return nil
+}
+
+// Check parses and compiles policy sources, or the bundle of a policy image, with the capabilities of each of the
+// OPA versions, embedded in the binary.
+func (c *Client) Check(ctx context.Context, opts *CheckOptions) (*CheckReport, error) {
+	if len(opts.OPAVersions) == 0 {
+		return nil, errors.New("no OPA versions to check")
+	}
+
+	var (
+		modules []runtime.SourceModule
+		stubs   []runtime.StubBuiltin
+		err     error
+	)
+
+	if len(opts.Paths) == 1 && !pathExists(opts.Paths[0]) {
+		modules, stubs, err = c.imageSourceModules(ctx, opts.Paths[0], opts.RegoVersion)
+	} else {
+		modules, stubs, err = runtime.LoadSourceModules(opts.Paths, opts.Ignore, opts.RegoVersion)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CheckReport{}
+
+	for _, requested := range opts.OPAVersions {
+		version, err := runtime.ResolveOPAVersion(requested)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := runtime.CheckCompatibility(modules, stubs, version)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Requested = requested
+		report.Versions = append(report.Versions, *result)
+
+		if result.Compatible && (report.MinimumVersion == "" || semver.Compare(version, "v"+report.MinimumVersion) < 0) {
+			report.MinimumVersion = strings.TrimPrefix(version, "v")
+		}
+	}
+
+	return report, nil
+}
+
+// imageSourceModules returns the modules of the bundle of a policy image, pulled when not in the local store.
+func (c *Client) imageSourceModules(
+	ctx context.Context,
+	ref string,
+	regoVersion RegoVersion,
+) ([]runtime.SourceModule, []runtime.StubBuiltin, error) {
+	ociClient, err := c.newOCI(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	descriptor, err := c.localDescriptor(ctx, ociClient, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loadedBundle, err := c.loadBundle(ctx, ociClient, descriptor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return runtime.BundleModules(loadedBundle, regoVersion.ToAstRegoVersion())
+}
+
+// minOPAVersion returns the lowest of the OPA versions of a build that can load its sources, it fails when none can.
+func (c *Client) minOPAVersion(ctx context.Context, opts *BuildOptions) (string, error) {
+	report, err := c.Check(ctx, &CheckOptions{
+		Paths:       opts.Paths,
+		Ignore:      opts.Ignore,
+		RegoVersion: opts.RegoVersion,
+		OPAVersions: opts.OPAVersions,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if report.MinimumVersion == "" {
+		reasons := []string{}
+		for _, v := range report.Versions {
+			reasons = append(reasons, v.Requested+": "+strings.Join(v.Errors, "; "))
+		}
+
+		return "", perr.ErrCompileFailed.WithMessage(
+			"policy cannot be loaded by any of the OPA versions, %s", strings.Join(reasons, ", "))
+	}
+
+	return report.MinimumVersion, nil
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+	goruntime "runtime"
+
+	"github.com/containerd/containerd/v2/core/remotes/docker"
+	"github.com/opcr-io/policy/internal/oci"
+	"github.com/opcr-io/policy/internal/runtime"
+	"github.com/opcr-io/policy/pkg/cc/config"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Client is the library API to build, store, transfer and evaluate policy images.
+// Unlike PolicyApp it performs no terminal I/O, every call takes its context from the caller
+// and returns a typed result.
+type Client struct {
+	config   *config.Config
+	logger   *zerolog.Logger
+	progress ProgressFunc
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// Progress tracks the transfer of a single blob.
+type Progress = oci.Progress
+
+// ProgressFunc starts tracking the transfer of the given descriptor.
+type ProgressFunc = oci.ProgressFunc
+
+// RegoVersion selects the Rego syntax version policies are compiled with.
+type RegoVersion = runtime.RegoVersion
+
+const (
+	RegoV0         = runtime.RegoV0
+	RegoV0CompatV1 = runtime.RegoV0CompatV1
+	RegoV1         = runtime.RegoV1
+)
+
+// WithLogger sets the logger used by the client, logs are discarded by default.
+func WithLogger(logger *zerolog.Logger) ClientOption {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithProgress reports the progress of blob transfers to fn.
+func WithProgress(fn ProgressFunc) ClientOption {
+	return func(c *Client) {
+		c.progress = fn
+	}
+}
+
+// NewClient creates a Client for the local store and registries described by cfg.
+func NewClient(cfg *config.Config, opts ...ClientOption) *Client {
+	discard := zerolog.Nop()
+
+	c := &Client{
+		config: cfg,
+		logger: &discard,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// client returns a Client sharing the configuration and logger of the CLI, reporting progress through the UI.
+func (c *PolicyApp) client() *Client {
+	return NewClient(c.Configuration, WithLogger(c.Logger), WithProgress(c.transferProgress))
+}
+
+func (c *Client) newOCI(ctx context.Context) (*oci.Oci, error) {
+	ociClient, err := oci.NewOCI(ctx, c.logger, c.hosts, c.config.PoliciesRoot())
+	if err != nil {
+		return nil, err
+	}
+
+	if c.progress != nil {
+		ociClient.SetProgress(c.progress)
+	}
+
+	return ociClient, nil
+}
+
+// track starts tracking the transfer of desc, transfers are not reported without a progress option.
+func (c *Client) track(desc v1.Descriptor) Progress {
+	if c.progress == nil {
+		return oci.NopProgress{}
+	}
+
+	return c.progress(desc)
+}
+
+func (c *Client) hosts(server string) ([]docker.RegistryHost, error) {
+	transport, err := c.Transport()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Transport: transport}
+
+	scheme := "https"
+	if c.config.Plaintext {
+		scheme = "http"
+	}
+
+	return []docker.RegistryHost{
+		{
+			Host:         server,
+			Scheme:       scheme,
+			Capabilities: docker.HostCapabilityPull | docker.HostCapabilityResolve | docker.HostCapabilityPush,
+			Client:       client,
+			Path:         "/v2",
+			Authorizer: docker.NewDockerAuthorizer(
+				docker.WithAuthClient(client),
+				docker.WithAuthCreds(func(s string) (string, string, error) {
+					creds, err := c.config.CredentialsStore.Get(s)
+					if err != nil || (creds.Username == "" && creds.Password == "") {
+						return " ", " ", nil //nolint:nilerr
+					}
+
+					return creds.Username, creds.Password, nil
+				})),
+		},
+	}, nil
+}
+
+// Transport returns an HTTP transport that trusts the system and configured CAs.
+func (c *Client) Transport() (*http.Transport, error) {
+	if c.config.Insecure {
+		return &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}, nil //nolint:gosec // feature used for debugging
+	}
+
+	if goruntime.GOOS == `windows` {
+		if len(c.config.CA) > 0 {
+			c.logger.Warn().Msg("Cannot use custom CAs on Windows. Please configure your system store to trust your CAs.")
+		}
+
+		return http.DefaultTransport.(*http.Transport), nil //nolint:forcetypeassert
+	}
+
+	// Get the SystemCertPool, continue with an empty pool on error
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load system cert pool")
+	}
+
+	if rootCAs == nil {
+		rootCAs = x509.NewCertPool()
+	}
+
+	// Read in the cert files
+	for _, localCertFile := range c.config.CA {
+		certs, err := os.ReadFile(localCertFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to append %q to RootCAs", localCertFile)
+		}
+
+		// Append our cert to the system pool
+		if ok := rootCAs.AppendCertsFromPEM(certs); !ok {
+			c.logger.Warn().Str("cert", localCertFile).Msg("Cert not appended to RootCAs, using system certs only.")
+		}
+	}
+
+	// Trust the augmented cert pool in our client
+	tlsConfig := &tls.Config{
+		RootCAs:    rootCAs,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	return &http.Transport{TLSClientConfig: tlsConfig}, nil
+}
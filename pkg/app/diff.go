@@ -0,0 +1,503 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/opcr-io/policy/internal/oci"
+	"github.com/opcr-io/policy/internal/parser"
+	"github.com/opcr-io/policy/internal/runtime"
+	perr "github.com/opcr-io/policy/pkg/errors"
+	"github.com/opcr-io/policy/pkg/table"
+
+	"github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// identifier matches the object keys written as '.key' in the paths of data changes, others are written as '["key"]'.
+var identifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func (c *PolicyApp) Diff(fromRef, toRef string) error {
+	defer c.Cancel()
+
+	diff, err := c.client().Diff(c.Context, fromRef, toRef)
+	if err != nil {
+		return err
+	}
+
+	if c.Output.Structured() {
+		return c.Output.Print(diff)
+	}
+
+	if diff.Empty() {
+		c.UI.Normal().Msgf("No differences between [%s] and [%s].", diff.From, diff.To)
+		return nil
+	}
+
+	if len(diff.Modules) > 0 {
+		c.UI.Normal().Msg("Modules")
+
+		data := [][]any{}
+		for _, m := range diff.Modules {
+			data = append(data, []any{m.File, m.Change})
+		}
+
+		t := table.New(os.Stdout)
+		t.Header("File", "Change")
+		t.Bulk(data)
+		t.Render()
+
+		for _, m := range diff.Modules {
+			fmt.Fprint(os.Stdout, m.Diff)
+		}
+	}
+
+	sections := []struct {
+		title   string
+		header  string
+		changes []ValueChange
+	}{
+		{"Data", "Path", diff.Data},
+		{"Manifest", "Field", diff.Manifest},
+		{"Annotations", "Annotation", diff.Annotations},
+	}
+
+	for _, section := range sections {
+		if len(section.changes) == 0 {
+			continue
+		}
+
+		c.UI.Normal().Msg(section.title)
+
+		data := [][]any{}
+		for _, change := range section.changes {
+			data = append(data, []any{change.Path, change.Change, displayValue(change.From), displayValue(change.To)})
+		}
+
+		t := table.New(os.Stdout)
+		t.Header(section.header, "Change", "From", "To")
+		t.Bulk(data)
+		t.Render()
+	}
+
+	return nil
+}
+
+// Diff compares the bundles and the annotations of two policy images of the local store.
+func (c *Client) Diff(ctx context.Context, fromRef, toRef string) (*ImageDiff, error) {
+	ociClient, err := c.newOCI(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := c.loadImage(ctx, ociClient, fromRef)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := c.loadImage(ctx, ociClient, toRef)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &ImageDiff{
+		From:        from.ref,
+		To:          to.ref,
+		Modules:     []ModuleChange{},
+		Data:        []ValueChange{},
+		Manifest:    []ValueChange{},
+		Annotations: []ValueChange{},
+	}
+
+	if err := diff.diffModules(from.bundle, to.bundle); err != nil {
+		return nil, err
+	}
+
+	diffValues("$", normalize(from.bundle.Data), normalize(to.bundle.Data), &diff.Data)
+
+	if err := diff.diffManifests(&from.bundle.Manifest, &to.bundle.Manifest); err != nil {
+		return nil, err
+	}
+
+	for _, key := range unionKeys(from.annotations, to.annotations) {
+		fromValue, inFrom := from.annotations[key]
+		toValue, inTo := to.annotations[key]
+
+		if change := valueChange(key, fromValue, inFrom, toValue, inTo); change != nil {
+			diff.Annotations = append(diff.Annotations, *change)
+		}
+	}
+
+	return diff, nil
+}
+
+type loadedImage struct {
+	ref         string
+	bundle      *bundle.Bundle
+	annotations map[string]string
+}
+
+// loadImage reads the bundle and the annotations of an image of the local store, images are not pulled.
+func (c *Client) loadImage(ctx context.Context, ociClient *oci.Oci, userRef string) (*loadedImage, error) {
+	ref, err := parser.CalculateRef(userRef, c.config.DefaultDomain)
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := ociClient.ListReferences()
+	if err != nil {
+		return nil, err
+	}
+
+	descriptor, ok := refs[ref]
+	if !ok {
+		return nil, perr.ErrNotFound.WithMessage("policy [%s] not in the local store", ref)
+	}
+
+	b, err := c.loadBundle(ctx, ociClient, descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	annotations, err := getAnnotations(&descriptor, ociClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &loadedImage{ref: ref, bundle: b, annotations: annotations}, nil
+}
+
+func (d *ImageDiff) diffModules(from, to *bundle.Bundle) error {
+	fromModules := map[string]string{}
+	for _, m := range from.Modules {
+		fromModules[m.Path] = string(m.Raw)
+	}
+
+	toModules := map[string]string{}
+	for _, m := range to.Modules {
+		toModules[m.Path] = string(m.Raw)
+	}
+
+	for _, file := range unionKeys(fromModules, toModules) {
+		fromSrc, inFrom := fromModules[file]
+		toSrc, inTo := toModules[file]
+
+		change := ModuleChange{File: file}
+
+		switch {
+		case !inFrom:
+			change.Change = ChangeAdded
+		case !inTo:
+			change.Change = ChangeRemoved
+		case fromSrc != toSrc:
+			change.Change = ChangeModified
+		default:
+			continue
+		}
+
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        sourceLines(fromSrc),
+			B:        sourceLines(toSrc),
+			FromFile: d.From + ":" + file,
+			ToFile:   d.To + ":" + file,
+			Context:  3,
+		})
+		if err != nil {
+			return err
+		}
+
+		change.Diff = diff
+		d.Modules = append(d.Modules, change)
+	}
+
+	return nil
+}
+
+func (d *ImageDiff) diffManifests(from, to *bundle.Manifest) error {
+	fields := []struct {
+		name     string
+		from, to any
+	}{
+		{"roots", sortedRoots(from.Roots), sortedRoots(to.Roots)},
+		{"revision", from.Revision, to.Revision},
+		{"rego_version", regoVersionName(from.RegoVersion), regoVersionName(to.RegoVersion)},
+	}
+
+	for _, field := range fields {
+		if change := valueChange(field.name, field.from, true, field.to, true); change != nil {
+			d.Manifest = append(d.Manifest, *change)
+		}
+	}
+
+	fromBuiltins, err := requiredBuiltins(from)
+	if err != nil {
+		return err
+	}
+
+	toBuiltins, err := requiredBuiltins(to)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range unionKeys(fromBuiltins, toBuiltins) {
+		fromDecl, inFrom := fromBuiltins[name]
+		toDecl, inTo := toBuiltins[name]
+
+		if change := valueChange("required_builtins."+name, fromDecl, inFrom, toDecl, inTo); change != nil {
+			d.Manifest = append(d.Manifest, *change)
+		}
+	}
+
+	return nil
+}
+
+// requiredBuiltins returns the declarations of the required builtins of a manifest by name, as JSON documents.
+func requiredBuiltins(manifest *bundle.Manifest) (map[string]any, error) {
+	buf, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := runtime.MetadataEx{}
+	if err := json.Unmarshal(buf, &metadata); err != nil {
+		return nil, err
+	}
+
+	builtins := map[string]any{}
+	if metadata.Metadata.RequiredBuiltins == nil {
+		return builtins, nil
+	}
+
+	for _, builtin := range metadata.Metadata.RequiredBuiltins.All() {
+		buf, err := json.Marshal(&builtin.Decl)
+		if err != nil {
+			return nil, err
+		}
+
+		var decl any
+		if err := json.Unmarshal(buf, &decl); err != nil {
+			return nil, err
+		}
+
+		builtins[builtin.Name] = decl
+	}
+
+	return builtins, nil
+}
+
+// diffValues appends the changes between two JSON documents, objects and arrays are compared by member.
+func diffValues(path string, from, to any, changes *[]ValueChange) {
+	switch fromValue := from.(type) {
+	case map[string]any:
+		toValue, ok := to.(map[string]any)
+		if !ok {
+			break
+		}
+
+		for _, key := range unionKeys(fromValue, toValue) {
+			fromMember, inFrom := fromValue[key]
+			toMember, inTo := toValue[key]
+
+			if inFrom && inTo {
+				diffValues(memberPath(path, key), fromMember, toMember, changes)
+			} else if change := valueChange(memberPath(path, key), fromMember, inFrom, toMember, inTo); change != nil {
+				*changes = append(*changes, *change)
+			}
+		}
+
+		return
+	case []any:
+		toValue, ok := to.([]any)
+		if !ok {
+			break
+		}
+
+		for i := range max(len(fromValue), len(toValue)) {
+			elemPath := fmt.Sprintf("%s[%d]", path, i)
+
+			switch {
+			case i >= len(fromValue):
+				*changes = append(*changes, ValueChange{Path: elemPath, Change: ChangeAdded, To: toValue[i]})
+			case i >= len(toValue):
+				*changes = append(*changes, ValueChange{Path: elemPath, Change: ChangeRemoved, From: fromValue[i]})
+			default:
+				diffValues(elemPath, fromValue[i], toValue[i], changes)
+			}
+		}
+
+		return
+	}
+
+	if change := valueChange(path, from, true, to, true); change != nil {
+		*changes = append(*changes, *change)
+	}
+}
+
+func valueChange(path string, from any, inFrom bool, to any, inTo bool) *ValueChange {
+	switch {
+	case !inFrom && !inTo:
+		return nil
+	case !inFrom:
+		return &ValueChange{Path: path, Change: ChangeAdded, To: to}
+	case !inTo:
+		return &ValueChange{Path: path, Change: ChangeRemoved, From: from}
+	case equalValues(from, to):
+		return nil
+	default:
+		return &ValueChange{Path: path, Change: ChangeModified, From: from, To: to}
+	}
+}
+
+// sourceLines splits a module source for a unified diff, a missing module has no lines.
+func sourceLines(src string) []string {
+	if src == "" {
+		return nil
+	}
+
+	return difflib.SplitLines(src)
+}
+
+func memberPath(path, key string) string {
+	if identifier.MatchString(key) {
+		return path + "." + key
+	}
+
+	buf, _ := json.Marshal(key)
+
+	return path + "[" + string(buf) + "]"
+}
+
+// normalize converts a data document to the plain JSON types. Numbers are kept as json.Number, as bundles do, so
+// that integers above 2^53 keep their precision.
+func normalize(data any) any {
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return data
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return data
+	}
+
+	return v
+}
+
+// equalValues compares normalized values, numbers by their value rather than their text, as 1 and 1.0.
+func equalValues(from, to any) bool {
+	switch fromValue := from.(type) {
+	case json.Number:
+		toValue, ok := to.(json.Number)
+		if !ok {
+			return false
+		}
+
+		x, xOk := new(big.Rat).SetString(fromValue.String())
+		y, yOk := new(big.Rat).SetString(toValue.String())
+		if !xOk || !yOk {
+			return fromValue == toValue
+		}
+
+		return x.Cmp(y) == 0
+	case map[string]any:
+		toValue, ok := to.(map[string]any)
+		if !ok || len(fromValue) != len(toValue) {
+			return false
+		}
+
+		for key, fromMember := range fromValue {
+			toMember, ok := toValue[key]
+			if !ok || !equalValues(fromMember, toMember) {
+				return false
+			}
+		}
+
+		return true
+	case []any:
+		toValue, ok := to.([]any)
+
+		return ok && slices.EqualFunc(fromValue, toValue, equalValues)
+	default:
+		return reflect.DeepEqual(from, to)
+	}
+}
+
+func sortedRoots(roots *[]string) []string {
+	if roots == nil {
+		return []string{}
+	}
+
+	sorted := slices.Clone(*roots)
+	sort.Strings(sorted)
+
+	return sorted
+}
+
+func regoVersionName(version *int) string {
+	if version == nil {
+		return ""
+	}
+
+	switch *version {
+	case 0:
+		return "rego.v0"
+	case 1:
+		return "rego.v1"
+	default:
+		return fmt.Sprintf("%d", *version)
+	}
+}
+
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := []string{}
+
+	for key := range a {
+		keys = append(keys, key)
+	}
+
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// displayValue renders a value of a change on one line of a table.
+func displayValue(v any) string {
+	if v == nil {
+		return ""
+	}
+
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return strings.TrimSpace(string(buf))
+}
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/opcr-io/policy/internal/runtime"
+	perr "github.com/opcr-io/policy/pkg/errors"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/pkg/errors"
+)
+
+// AnnotationDocs records the documentation summary of an image built with docs, a JSON list of packages.
+const AnnotationDocs = "org.openpolicyregistry.docs"
+
+// DocOptions describes the documentation of policy sources, or of a policy image when Paths is a single reference
+// that is not a path.
+type DocOptions struct {
+	Paths       []string
+	Ignore      []string
+	RegoVersion RegoVersion
+	// HTML renders an HTML page instead of Markdown.
+	HTML bool
+	// Output is the file the documentation is written to, stdout when empty.
+	Output string
+}
+
+func (c *PolicyApp) Doc(opts *DocOptions) error {
+	defer c.Cancel()
+
+	docs, err := c.client().Doc(c.Context, opts)
+	if err != nil {
+		return err
+	}
+
+	if c.Output.Structured() {
+		return c.Output.Print(docs)
+	}
+
+	out := &bytes.Buffer{}
+
+	if opts.HTML {
+		err = runtime.RenderHTML(out, docs)
+	} else {
+		err = runtime.RenderMarkdown(out, docs)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if opts.Output == "" {
+		_, err := io.Copy(os.Stdout, out)
+		return err
+	}
+
+	if err := os.WriteFile(opts.Output, out.Bytes(), 0o600); err != nil {
+		return errors.Wrapf(err, "failed to write [%s]", opts.Output)
+	}
+
+	c.UI.Normal().
+		WithIntValue("packages", int64(len(docs))).
+		Msgf("Documentation written to [%s].", opts.Output)
+
+	return nil
+}
+
+// Doc collects the documentation of the packages of policy sources, or of the bundle of a policy image.
+func (c *Client) Doc(ctx context.Context, opts *DocOptions) ([]runtime.PackageDoc, error) {
+	var (
+		modules []*ast.Module
+		err     error
+	)
+
+	if len(opts.Paths) == 1 && !pathExists(opts.Paths[0]) {
+		modules, err = c.imageModules(ctx, opts.Paths[0])
+	} else {
+		modules, err = runtime.LoadAnnotatedModules(opts.Paths, opts.Ignore, opts.RegoVersion)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := runtime.Document(modules)
+	if err != nil {
+		return nil, perr.ErrCompileFailed.WithError(err)
+	}
+
+	return docs, nil
+}
+
+// imageModules returns the parsed modules of the bundle of a policy image, pulled when not in the local store.
+func (c *Client) imageModules(ctx context.Context, ref string) ([]*ast.Module, error) {
+	ociClient, err := c.newOCI(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptor, err := c.localDescriptor(ctx, ociClient, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	loadedBundle, err := c.loadBundle(ctx, ociClient, descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(loadedBundle.Modules, func(i, j int) bool {
+		return loadedBundle.Modules[i].Path < loadedBundle.Modules[j].Path
+	})
+
+	modules := make([]*ast.Module, 0, len(loadedBundle.Modules))
+	for _, m := range loadedBundle.Modules {
+		modules = append(modules, m.Parsed)
+	}
+
+	return modules, nil
+}
+
+// docsAnnotation returns the documentation summary of policy sources as recorded on images.
+func docsAnnotation(opts *BuildOptions) (string, error) {
+	modules, err := runtime.LoadAnnotatedModules(opts.Paths, opts.Ignore, opts.RegoVersion)
+	if err != nil {
+		return "", err
+	}
+
+	docs, err := runtime.Document(modules)
+	if err != nil {
+		return "", err
+	}
+
+	buf, err := json.Marshal(runtime.Summarize(docs))
+	if err != nil {
+		return "", err
+	}
+
+	return string(buf), nil
+}
+
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
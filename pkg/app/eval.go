@@ -0,0 +1,272 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opcr-io/policy/internal/oci"
+	"github.com/opcr-io/policy/internal/parser"
+	"github.com/opcr-io/policy/internal/runtime"
+	perr "github.com/opcr-io/policy/pkg/errors"
+	"github.com/opcr-io/policy/pkg/output"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/bundle"
+	"github.com/open-policy-agent/opa/v1/metrics"
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// EvalOptions describes the evaluation of a query against a policy image.
+type EvalOptions struct {
+	// Reference is the policy image, pulled when it is not in the local store.
+	Reference string
+	// Query defaults to the entrypoint recorded on the image, when it has exactly one.
+	Query string
+	Input any
+	// Mocks answers the calls to the stub builtins of the policy, which return null without.
+	Mocks *Mocks
+	// Schema is a JSON schema file for the input, or a directory of schemas, the query and the policy are type-checked
+	// against. The schemas stored with the image are used by default.
+	Schema string
+}
+
+func (c *PolicyApp) Eval(opts *EvalOptions) error {
+	defer c.Cancel()
+
+	results, err := c.client().Eval(c.Context, opts)
+	if err != nil {
+		return err
+	}
+
+	c.reportUnmocked(opts.Mocks)
+
+	if c.Output.Structured() {
+		return c.Output.Print(results)
+	}
+
+	// like opa eval, the result set is printed as JSON by default.
+	printer, err := output.New(c.UI.Output(), output.FormatJSON)
+	if err != nil {
+		return err
+	}
+
+	return printer.Print(results)
+}
+
+// Eval evaluates a query against the bundle of a policy image.
+func (c *Client) Eval(ctx context.Context, opts *EvalOptions) (rego.ResultSet, error) {
+	ociClient, err := c.newOCI(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stop, err := c.startProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+
+	descriptor, err := c.localDescriptor(ctx, ociClient, opts.Reference)
+	if err != nil {
+		return nil, err
+	}
+
+	query := opts.Query
+	if query == "" {
+		query, err = entrypointQuery(ociClient, descriptor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	schemas, err := loadSchemas(opts.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	if schemas == nil {
+		schemas, err = c.imageSchemas(ctx, ociClient, descriptor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ss, err := schemas.SchemaSet()
+	if err != nil {
+		return nil, err
+	}
+
+	loadedBundle, err := c.loadBundle(ctx, ociClient, descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []func(*rego.Rego){
+		rego.Query(query),
+		rego.ParsedBundle("default", loadedBundle),
+		rego.Schemas(ss),
+	}
+
+	if opts.Input != nil {
+		options = append(options, rego.Input(opts.Input))
+	}
+
+	return rego.New(options...).Eval(runtime.WithMocks(ctx, opts.Mocks))
+}
+
+// entrypointQuery returns the query of the single entrypoint recorded on an image.
+func entrypointQuery(ociClient *oci.Oci, descriptor v1.Descriptor) (string, error) {
+	annotations, err := getAnnotations(&descriptor, ociClient)
+	if err != nil {
+		return "", err
+	}
+
+	var entrypoints []string
+
+	if recorded, ok := annotations[AnnotationEntrypoints]; ok {
+		if err := json.Unmarshal([]byte(recorded), &entrypoints); err != nil {
+			return "", errors.Wrap(err, "invalid entrypoints annotation")
+		}
+	}
+
+	switch len(entrypoints) {
+	case 0:
+		return "", errors.New("no query given and the policy has no entrypoints")
+	case 1:
+		return runtime.EntrypointQuery(entrypoints[0])
+	default:
+		return "", errors.Errorf("no query given and the policy has %d entrypoints, query one of: %s",
+			len(entrypoints), strings.Join(entrypoints, ", "))
+	}
+}
+
+// localDescriptor resolves a reference in the local store, pulling it from the registry when it is missing.
+func (c *Client) localDescriptor(ctx context.Context, ociClient *oci.Oci, userRef string) (v1.Descriptor, error) {
+	ref, err := parser.CalculateRef(userRef, c.config.DefaultDomain)
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+
+	existingRefs, err := ociClient.ListReferences()
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+
+	if descriptor, ok := existingRefs[ref]; ok {
+		return descriptor, nil
+	}
+
+	if _, err := c.Pull(ctx, userRef); err != nil {
+		return v1.Descriptor{}, err
+	}
+
+	existingRefs, err = ociClient.ListReferences()
+	if err != nil {
+		return v1.Descriptor{}, err
+	}
+
+	descriptor, ok := existingRefs[ref]
+	if !ok {
+		return v1.Descriptor{}, perr.ErrNotFound.WithMessage("policy [%s] not in the local store", userRef)
+	}
+
+	return descriptor, nil
+}
+
+// loadBundle reads the bundle of a policy image and registers stubs for the builtins it requires.
+func (c *Client) loadBundle(ctx context.Context, ociClient *oci.Oci, descriptor v1.Descriptor) (*bundle.Bundle, error) {
+	// check for media type - if manifest get tarball digest hex.
+	bundleHex, err := c.getBundleHex(ctx, ociClient, &descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	bundleFile := filepath.Join(c.config.PoliciesRoot(), "blobs", "sha256", bundleHex)
+
+	reader, err := os.Open(bundleFile)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	loader := bundle.NewTarballLoaderWithBaseURL(reader, "")
+
+	// annotations are kept for rego.metadata and the documentation of the bundle.
+	bundleReader := bundle.NewCustomReader(loader).WithProcessAnnotations(true)
+
+	loadedBundle, err := bundleReader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	requiredBuiltins, err := runtime.ManifestBuiltins(&loadedBundle.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	runtime.RegisterStubBuiltins(requiredBuiltins)
+
+	return &loadedBundle, nil
+}
+
+// loadStore activates a bundle in a new in-memory store, the returned compiler holds its compiled modules.
+func (c *Client) loadStore(ctx context.Context, loadedBundle *bundle.Bundle) (storage.Store, *ast.Compiler, error) {
+	store := inmem.New()
+
+	txn, err := store.NewTransaction(ctx, storage.WriteParams)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	compiler := ast.NewCompiler()
+
+	opts := bundle.ActivateOpts{
+		Ctx:      ctx,
+		Store:    store,
+		Txn:      txn,
+		Compiler: compiler,
+		Metrics:  metrics.New(),
+		Bundles: map[string]*bundle.Bundle{
+			"default": loadedBundle,
+		},
+	}
+
+	err = bundle.Activate(&opts)
+	if err != nil {
+		store.Abort(ctx, txn)
+		return nil, nil, err
+	}
+
+	if err := store.Commit(ctx, txn); err != nil {
+		return nil, nil, err
+	}
+
+	return store, compiler, nil
+}
+
+func (c *Client) getBundleHex(ctx context.Context, ociClient *oci.Oci, descriptor *v1.Descriptor) (string, error) {
+	var bundleHex string
+	// check for media type - if manifest get tarbarll digest hex.
+	if descriptor.MediaType == v1.MediaTypeImageManifest {
+		bundleDescriptor, _, err := ociClient.GetTarballAndConfigLayerDescriptor(ctx, descriptor)
+		if err != nil {
+			return "", err
+		}
+
+		bundleHex = bundleDescriptor.Digest.Hex()
+		if bundleHex == "" {
+			return "", perr.ErrReplFailed.WithMessage("current manifest does not contain a MediaTypeImageLayerGzip")
+		}
+	} else {
+		bundleHex = descriptor.Digest.Hex()
+	}
+
+	return bundleHex, nil
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/opcr-io/policy/internal/runtime"
+	perr "github.com/opcr-io/policy/pkg/errors"
+	"github.com/opcr-io/policy/pkg/x"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// FmtOptions describes the formatting of policy sources.
+type FmtOptions struct {
+	Paths       []string
+	Ignore      []string
+	RegoVersion RegoVersion
+	// Check reports the files that are not formatted without writing them.
+	Check bool
+	// Diff prints the changes formatting makes without writing them.
+	Diff bool
+}
+
+// FmtResult lists the files changed, or that would change, by formatting.
+type FmtResult struct {
+	Files   []string `json:"files"`
+	Diffs   []string `json:"diffs,omitempty"`
+	Written bool     `json:"written"`
+}
+
+func (c *PolicyApp) Fmt(opts *FmtOptions) error {
+	defer c.Cancel()
+
+	result, err := c.client().Fmt(opts)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case c.Output.Structured():
+		if err := c.Output.Print(result); err != nil {
+			return err
+		}
+	case opts.Diff:
+		for _, diff := range result.Diffs {
+			fmt.Fprint(os.Stdout, diff)
+		}
+	default:
+		for _, file := range result.Files {
+			fmt.Fprintln(os.Stdout, file)
+		}
+	}
+
+	if opts.Check && len(result.Files) > 0 {
+		return errors.Errorf("%d files are not formatted", len(result.Files))
+	}
+
+	return nil
+}
+
+// Fmt applies the canonical formatting to policy sources, files are only written without check and diff.
+func (c *Client) Fmt(opts *FmtOptions) (*FmtResult, error) {
+	modules, err := runtime.Format(&runtime.FormatParams{
+		Ignore:      opts.Ignore,
+		RegoVersion: opts.RegoVersion,
+	}, opts.Paths)
+	if err != nil {
+		var astErrs ast.Errors
+		if errors.As(err, &astErrs) {
+			return nil, perr.ErrCompileFailed.WithError(err)
+		}
+
+		return nil, err
+	}
+
+	result := &FmtResult{Files: []string{}}
+
+	for _, m := range modules {
+		result.Files = append(result.Files, m.File)
+
+		if !opts.Diff {
+			continue
+		}
+
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(m.Source)),
+			B:        difflib.SplitLines(string(m.Formatted)),
+			FromFile: m.File,
+			ToFile:   m.File + " (formatted)",
+			Context:  3,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		result.Diffs = append(result.Diffs, diff)
+	}
+
+	if opts.Check || opts.Diff {
+		return result, nil
+	}
+
+	for _, m := range modules {
+		info, err := os.Stat(m.File)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := os.WriteFile(m.File, m.Formatted, info.Mode().Perm()|x.OwnerReadWrite); err != nil {
+			return nil, errors.Wrapf(err, "failed to write [%s]", m.File)
+		}
+	}
+
+	result.Written = len(modules) > 0
+
+	return result, nil
+}
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"os"
 	"slices"
 	"sort"
@@ -8,58 +9,80 @@ import (
 
 	"github.com/distribution/reference"
 	"github.com/dustin/go-humanize"
-	"github.com/opcr-io/policy/internal/oci"
 	"github.com/opcr-io/policy/internal/parser"
 	"github.com/opcr-io/policy/pkg/table"
 
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
-type imageStruct struct {
-	familiarName string
-	tagOrNone    string
-	digest       string
-	createdAt    string
-	size         string
-}
-
-//nolint:funlen
 func (c *PolicyApp) Images() error {
 	defer c.Cancel()
 
-	var images []imageStruct
-
-	ociClient, err := oci.NewOCI(c.Context, c.Logger, c.getHosts, c.Configuration.PoliciesRoot())
+	images, err := c.client().List(c.Context)
 	if err != nil {
 		return err
 	}
 
+	if c.Output.Structured() {
+		return c.Output.Print(images)
+	}
+
+	data := [][]any{}
+	for _, image := range images {
+		data = append(data, []any{
+			image.display,
+			image.Tag,
+			image.ImageID,
+			image.Created,
+			strings.ReplaceAll(humanize.Bytes(uint64(image.Size)), " ", ""), //nolint: gosec
+		})
+	}
+
+	t := table.New(os.Stdout)
+	t.Header("Repository", "Tag", "Image ID", "Created", "Size")
+	t.Bulk(data)
+	t.Render()
+
+	return nil
+}
+
+// List returns the images in the local store, most recently created first.
+//
+//nolint:funlen
+func (c *Client) List(ctx context.Context) ([]Image, error) {
+	var images []Image
+
+	ociClient, err := c.newOCI(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var tgs []string
 
-	if err := ociClient.GetStore().Tags(c.Context, "", func(tags []string) error {
+	if err := ociClient.GetStore().Tags(ctx, "", func(tags []string) error {
 		tgs = append(tgs, tags...)
 		return nil
 	}); err != nil {
-		return err
+		return nil, err
 	}
 
 	for _, tag := range tgs {
-		desc, err := ociClient.GetStore().Resolve(c.Context, tag)
+		desc, err := ociClient.GetStore().Resolve(ctx, tag)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		var manifest *v1.Manifest
 		if desc.MediaType == v1.MediaTypeImageManifest {
 			manifest, err = ociClient.GetManifest(&desc)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 
 		ref, err := reference.ParseDockerRef(tag)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		refName := ref.Name()
@@ -71,9 +94,9 @@ func (c *PolicyApp) Images() error {
 			tagOrNone = tag.Tag()
 		}
 
-		familiarName, err := parser.CalculateRef(refName, c.Configuration.DefaultDomain)
+		familiarName, err := parser.CalculateNamedRef(refName, c.config.DefaultDomain)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		var createdAt string
@@ -83,35 +106,23 @@ func (c *PolicyApp) Images() error {
 			createdAt = manifest.Annotations[v1.AnnotationCreated]
 		}
 
-		images = append(images, imageStruct{
-			familiarName: familiarName,
-			tagOrNone:    tagOrNone,
-			digest:       desc.Digest.Encoded()[:12],
-			createdAt:    createdAt,
-			size:         strings.ReplaceAll(humanize.Bytes(uint64(desc.Size)), " ", ""), //nolint: gosec
+		images = append(images, Image{
+			Repository: familiarName.Name(),
+			display:    familiarName.String(),
+			Tag:        tagOrNone,
+			ImageID:    shortDigest(desc),
+			Digest:     desc.Digest.String(),
+			Created:    createdAt,
+			Size:       desc.Size,
 		})
 	}
 
 	// sort data by CreatedAt DESC.
 	sort.SliceStable(images, func(i, j int) bool {
-		return images[i].createdAt < images[j].createdAt || (images[i].createdAt == images[j].createdAt && images[i].familiarName < images[j].familiarName)
+		return images[i].Created < images[j].Created || (images[i].Created == images[j].Created && images[i].Repository < images[j].Repository)
 	})
 
-	data := [][]any{}
-	for _, image := range slices.Backward(images) {
-		data = append(data, []any{
-			image.familiarName,
-			image.tagOrNone,
-			image.digest,
-			image.createdAt,
-			image.size,
-		})
-	}
-
-	t := table.New(os.Stdout)
-	t.Header("Repository", "Tag", "Image ID", "Created", "Size")
-	t.Bulk(data)
-	t.Render()
+	slices.Reverse(images)
 
-	return nil
+	return images, nil
 }
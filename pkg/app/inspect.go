@@ -1,7 +1,10 @@
 package app
 
 import (
+	"context"
+	"encoding/json"
 	"os"
+	"strings"
 
 	"github.com/opcr-io/policy/internal/oci"
 	"github.com/opcr-io/policy/internal/parser"
@@ -13,37 +16,39 @@ import (
 func (c *PolicyApp) Inspect(userRef string) error {
 	defer c.Cancel()
 
-	ref, err := parser.CalculateRef(userRef, c.Configuration.DefaultDomain)
+	details, err := c.client().Inspect(c.Context, userRef)
 	if err != nil {
 		return err
 	}
 
-	ociClient, err := oci.NewOCI(c.Context, c.Logger, c.getHosts, c.Configuration.PoliciesRoot())
-	if err != nil {
-		return err
+	if c.Output.Structured() {
+		return c.Output.Print(details)
 	}
 
-	contentInfo, err := ociClient.GetStore().Resolve(c.Context, ref)
-	if err != nil {
-		return errors.Wrapf(err, "failed to read content info for policy [%s]", ref)
+	msg := c.UI.Normal().
+		WithStringValue("media type", details.MediaType).
+		WithStringValue("digest", details.Digest).
+		WithIntValue("size", details.Size)
+
+	if len(details.Entrypoints) > 0 {
+		msg = msg.WithStringValue("entrypoints", strings.Join(details.Entrypoints, ", "))
 	}
 
-	c.UI.Normal().
-		WithStringValue("media type", contentInfo.MediaType).
-		WithStringValue("digest", contentInfo.Digest.String()).
-		WithIntValue("size", contentInfo.Size).
-		Do()
+	if len(details.Schemas) > 0 {
+		msg = msg.WithStringValue("schemas", strings.Join(details.Schemas, ", "))
+	}
+
+	if details.CapabilityProfile != "" {
+		msg = msg.WithStringValue("capability profile", details.CapabilityProfile)
+	}
+
+	msg.Do()
 
 	c.UI.Normal().
 		Msg("Annotations")
 
-	annotations, err := getAnnotations(&contentInfo, ociClient)
-	if err != nil {
-		return err
-	}
-
 	data := [][]any{}
-	for k, v := range annotations {
+	for k, v := range details.Annotations {
 		data = append(data, []any{k, v})
 	}
 
@@ -52,9 +57,79 @@ func (c *PolicyApp) Inspect(userRef string) error {
 	t.Bulk(data)
 	t.Render()
 
+	if len(details.Docs) > 0 {
+		c.UI.Normal().
+			Msg("Documentation")
+
+		data := [][]any{}
+		for _, pkg := range details.Docs {
+			data = append(data, []any{pkg.Package, pkg.Title, strings.Join(pkg.Entrypoints, ", ")})
+		}
+
+		t := table.New(os.Stdout)
+		t.Header("Package", "Title", "Entrypoints")
+		t.Bulk(data)
+		t.Render()
+	}
+
 	return nil
 }
 
+// Inspect returns the descriptor and annotations of a policy image in the local store.
+func (c *Client) Inspect(ctx context.Context, userRef string) (*ImageDetails, error) {
+	ref, err := parser.CalculateRef(userRef, c.config.DefaultDomain)
+	if err != nil {
+		return nil, err
+	}
+
+	ociClient, err := c.newOCI(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	contentInfo, err := ociClient.GetStore().Resolve(ctx, ref)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read content info for policy [%s]", ref)
+	}
+
+	annotations, err := getAnnotations(&contentInfo, ociClient)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &ImageDetails{
+		Reference:         ref,
+		MediaType:         contentInfo.MediaType,
+		Digest:            contentInfo.Digest.String(),
+		Size:              contentInfo.Size,
+		Annotations:       annotations,
+		CapabilityProfile: annotations[AnnotationCapabilityProfile],
+	}
+
+	schemas, err := c.imageSchemas(ctx, ociClient, contentInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(schemas) > 0 {
+		details.Schemas = schemas.Refs()
+	}
+
+	if entrypoints, ok := annotations[AnnotationEntrypoints]; ok {
+		if err := json.Unmarshal([]byte(entrypoints), &details.Entrypoints); err != nil {
+			return nil, errors.Wrapf(err, "invalid entrypoints annotation of policy [%s]", ref)
+		}
+	}
+
+	if summary, ok := annotations[AnnotationDocs]; ok {
+		if err := json.Unmarshal([]byte(summary), &details.Docs); err != nil {
+			return nil, errors.Wrapf(err, "invalid documentation annotation of policy [%s]", ref)
+		}
+	}
+
+	return details, nil
+}
+
 func getAnnotations(contentInfo *v1.Descriptor, ociClient *oci.Oci) (map[string]string, error) {
 	var annotations map[string]string
 
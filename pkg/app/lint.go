@@ -0,0 +1,185 @@
+package app
+
+import (
+	"os"
+
+	"github.com/opcr-io/policy/internal/runtime"
+	perr "github.com/opcr-io/policy/pkg/errors"
+	"github.com/opcr-io/policy/pkg/table"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/pkg/errors"
+)
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// LintOptions describes a lint run over policy sources.
+type LintOptions struct {
+	Paths                []string
+	Ignore               []string
+	CapabilitiesJSONFile string
+	RegoVersion          RegoVersion
+	// Disable lists the IDs of the lint rules that are not applied.
+	Disable []string
+}
+
+// LintReport lists the lint violations found in policy sources.
+type LintReport struct {
+	Violations []runtime.LintViolation `json:"violations"`
+}
+
+func (c *PolicyApp) Lint(opts *LintOptions) error {
+	defer c.Cancel()
+
+	report, err := c.client().Lint(opts)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case c.Output.SARIF():
+		if err := c.Output.Print(sarifLog(report.Violations)); err != nil {
+			return err
+		}
+	case c.Output.Structured():
+		if err := c.Output.Print(report); err != nil {
+			return err
+		}
+	case len(report.Violations) == 0:
+		c.UI.Normal().Msg("No lint violations found.")
+	default:
+		data := [][]any{}
+		for _, v := range report.Violations {
+			data = append(data, []any{v.File, v.Row, v.Rule, v.Message})
+		}
+
+		t := table.New(os.Stdout)
+		t.Header("File", "Line", "Rule", "Message")
+		t.Bulk(data)
+		t.Render()
+	}
+
+	if len(report.Violations) > 0 {
+		return errors.Errorf("%d lint violations found", len(report.Violations))
+	}
+
+	return nil
+}
+
+// Lint applies the lint rules to policy sources.
+func (c *Client) Lint(opts *LintOptions) (*LintReport, error) {
+	violations, err := runtime.Lint(&runtime.LintParams{
+		CapabilitiesJSONFile: opts.CapabilitiesJSONFile,
+		Ignore:               opts.Ignore,
+		RegoVersion:          opts.RegoVersion,
+		Disable:              opts.Disable,
+	}, opts.Paths)
+	if err != nil {
+		var astErrs ast.Errors
+		if errors.As(err, &astErrs) {
+			return nil, perr.ErrCompileFailed.WithError(err)
+		}
+
+		return nil, err
+	}
+
+	return &LintReport{Violations: violations}, nil
+}
+
+// LintViolationsError fails a build with lint violations.
+type LintViolationsError struct {
+	Violations []runtime.LintViolation
+}
+
+func (e *LintViolationsError) Error() string {
+	msg := "lint violations found:"
+	for i := range e.Violations {
+		msg += "\n  " + e.Violations[i].String()
+	}
+
+	return msg
+}
+
+type sarif struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// sarifLog converts lint violations to a SARIF 2.1.0 log, every violation is a warning.
+func sarifLog(violations []runtime.LintViolation) *sarif {
+	driver := sarifDriver{Name: "policy", InformationURI: "https://github.com/opcr-io/policy"}
+	for _, rule := range runtime.LintRules {
+		driver.Rules = append(driver.Rules, sarifRule{ID: rule.ID, ShortDescription: sarifMessage{Text: rule.Description}})
+	}
+
+	results := []sarifResult{}
+	for _, v := range violations {
+		results = append(results, sarifResult{
+			RuleID:  v.Rule,
+			Level:   "warning",
+			Message: sarifMessage{Text: v.Message},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: v.File},
+					Region:           sarifRegion{StartLine: v.Row, StartColumn: v.Col},
+				},
+			}},
+		})
+	}
+
+	return &sarif{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+}
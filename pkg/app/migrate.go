@@ -0,0 +1,106 @@
+package app
+
+import (
+	"os"
+	"strings"
+
+	"github.com/opcr-io/policy/internal/runtime"
+	perr "github.com/opcr-io/policy/pkg/errors"
+	"github.com/opcr-io/policy/pkg/table"
+	"github.com/opcr-io/policy/pkg/x"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/pkg/errors"
+)
+
+// MigrateOptions describes the migration of policy sources to a newer Rego version.
+type MigrateOptions struct {
+	Paths                []string
+	To                   RegoVersion
+	Ignore               []string
+	CapabilitiesJSONFile string
+	// Check reports the modules that would change without writing them.
+	Check bool
+}
+
+// MigrateResult lists the modules changed, or that would change, by a migration.
+type MigrateResult struct {
+	Modules []*runtime.MigratedModule `json:"modules"`
+	Written bool                      `json:"written"`
+}
+
+func (c *PolicyApp) Migrate(opts *MigrateOptions) error {
+	defer c.Cancel()
+
+	result, err := c.client().Migrate(opts)
+	if err != nil {
+		return err
+	}
+
+	if c.Output.Structured() {
+		if err := c.Output.Print(result); err != nil {
+			return err
+		}
+	} else if len(result.Modules) == 0 {
+		c.UI.Normal().Msgf("Nothing to migrate to %s.", opts.To)
+	} else {
+		data := [][]any{}
+		for _, m := range result.Modules {
+			data = append(data, []any{m.File, strings.Join(m.Changes, "\n")})
+		}
+
+		t := table.New(os.Stdout)
+		t.Header("File", "Changes")
+		t.Bulk(data)
+		t.Render()
+
+		if result.Written {
+			c.UI.Normal().Msgf("Migrated %d modules to %s.", len(result.Modules), opts.To)
+		}
+	}
+
+	if opts.Check && len(result.Modules) > 0 {
+		return errors.Errorf("%d modules need to be migrated to %s", len(result.Modules), opts.To)
+	}
+
+	return nil
+}
+
+// Migrate rewrites policy sources to a newer Rego version. The result is compiled before any file is written,
+// and nothing is written in check mode.
+func (c *Client) Migrate(opts *MigrateOptions) (*MigrateResult, error) {
+	modules, err := runtime.Migrate(&runtime.MigrateParams{
+		To:                   opts.To,
+		Ignore:               opts.Ignore,
+		CapabilitiesJSONFile: opts.CapabilitiesJSONFile,
+	}, opts.Paths)
+	if err != nil {
+		var astErrs ast.Errors
+		if errors.As(err, &astErrs) {
+			return nil, perr.ErrCompileFailed.WithError(err)
+		}
+
+		return nil, err
+	}
+
+	result := &MigrateResult{Modules: modules}
+
+	if opts.Check {
+		return result, nil
+	}
+
+	for _, m := range modules {
+		info, err := os.Stat(m.File)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := os.WriteFile(m.File, m.Migrated, info.Mode().Perm()|x.OwnerReadWrite); err != nil {
+			return nil, errors.Wrapf(err, "failed to write [%s]", m.File)
+		}
+	}
+
+	result.Written = len(modules) > 0
+
+	return result, nil
+}
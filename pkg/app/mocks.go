@@ -0,0 +1,28 @@
+package app
+
+import (
+	"github.com/opcr-io/policy/internal/runtime"
+	"github.com/opcr-io/policy/pkg/clui"
+)
+
+// Mocks answers the calls to the stub builtins of a policy, see LoadMocks.
+type Mocks = runtime.Mocks
+
+// LoadMocks reads a YAML or JSON mock file mapping stub builtin names to a fixed value, a table of values keyed by
+// the call arguments, or a function of the Rego module defined in the file.
+func LoadMocks(path string, regoVersion RegoVersion) (*Mocks, error) {
+	return runtime.LoadMocks(path, regoVersion)
+}
+
+// reportUnmocked warns about the stub builtins called without a mock, on stderr to keep results parseable.
+func (c *PolicyApp) reportUnmocked(mocks *Mocks) {
+	if mocks == nil {
+		return
+	}
+
+	ui := clui.NewUIWithOutput(c.UI.Err())
+
+	for _, name := range mocks.Unmocked() {
+		ui.Exclamation().Msgf("Stub builtin [%s] called without a mock, it returned null.", name)
+	}
+}
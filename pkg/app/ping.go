@@ -5,6 +5,7 @@ import (
 	"net/url"
 
 	"github.com/containerd/containerd/v2/core/remotes/docker"
+	perr "github.com/opcr-io/policy/pkg/errors"
 	"github.com/pkg/errors"
 )
 
@@ -60,7 +61,7 @@ func (c *PolicyApp) Ping(server, username, password string) error {
 	}
 
 	if err := authorizer.Authorize(c.Context, req2); err != nil {
-		return errors.Wrapf(err, "failed to authorize request for server [%s]", server)
+		return perr.ErrAuthFailed.WithError(errors.Wrapf(err, "failed to authorize request for server [%s]", server))
 	}
 
 	resp2, err := client.Do(req2)
@@ -76,7 +77,7 @@ func (c *PolicyApp) Ping(server, username, password string) error {
 	}()
 
 	if resp2.StatusCode != http.StatusOK {
-		return errors.Errorf("authentication to server [%s] failed, status [%s]", server, resp.Status)
+		return perr.ErrAuthFailed.WithMessage("authentication to server [%s] failed, status [%s]", server, resp2.Status)
 	}
 
 	return nil
@@ -8,6 +8,7 @@ import (
 
 	"github.com/opcr-io/policy/pkg/cc/config"
 	"github.com/opcr-io/policy/pkg/clui"
+	"github.com/opcr-io/policy/pkg/output"
 
 	"github.com/docker/cli/cli/config/types"
 )
@@ -19,6 +20,7 @@ type PolicyApp struct {
 	Logger        *zerolog.Logger
 	Configuration *config.Config
 	UI            *clui.UI
+	Output        *output.Printer
 }
 
 func (c *PolicyApp) SaveServerCreds(creds *types.AuthConfig) error {
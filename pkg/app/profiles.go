@@ -0,0 +1,219 @@
+package app
+
+import (
+	"context"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/opcr-io/policy/internal/oci"
+	"github.com/opcr-io/policy/internal/runtime"
+	perr "github.com/opcr-io/policy/pkg/errors"
+
+	"github.com/distribution/reference"
+	"github.com/open-policy-agent/opa/v1/ast"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// AnnotationCapabilityProfile records the capability profile an image was built with.
+const AnnotationCapabilityProfile = "org.openpolicyregistry.capability-profile"
+
+// capabilityProfile is a profile of the config resolved with the profiles it extends.
+type capabilityProfile struct {
+	Name         string
+	Capabilities *ast.Capabilities
+	// Denied are the built-in functions removed by the deny patterns of the profile.
+	Denied []string
+}
+
+// resolveProfile returns the capabilities of a profile, those of the nearest profile setting a capabilities file,
+// of capabilitiesJSONFile or of this version of OPA, without the built-ins denied along the profile and its parents.
+func (c *Client) resolveProfile(name, capabilitiesJSONFile string) (*capabilityProfile, error) {
+	lineage, err := c.profileLineage(name)
+	if err != nil {
+		return nil, err
+	}
+
+	deny := []string{}
+
+	file := ""
+
+	for _, profileName := range lineage {
+		profile := c.config.CapabilityProfiles[profileName]
+
+		deny = append(deny, profile.Deny...)
+
+		if file == "" {
+			file = profile.Capabilities
+		}
+	}
+
+	if file == "" {
+		file = capabilitiesJSONFile
+	}
+
+	capabilities, err := runtime.LoadCapabilities(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load capabilities of profile [%s]", name)
+	}
+
+	capabilities, denied, err := runtime.DenyBuiltins(capabilities, deny)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid capability profile [%s]", name)
+	}
+
+	return &capabilityProfile{Name: name, Capabilities: capabilities, Denied: denied}, nil
+}
+
+// profileLineage returns a profile followed by the profiles it extends, nearest first.
+func (c *Client) profileLineage(name string) ([]string, error) {
+	lineage := []string{}
+
+	for next := name; next != ""; next = c.config.CapabilityProfiles[next].Extends {
+		if _, ok := c.config.CapabilityProfiles[next]; !ok {
+			return nil, errors.Errorf("unknown capability profile [%s]", next)
+		}
+
+		if slices.Contains(lineage, next) {
+			return nil, errors.Errorf("capability profile [%s] extends itself through [%s]", name, strings.Join(lineage, " -> "))
+		}
+
+		lineage = append(lineage, next)
+	}
+
+	return lineage, nil
+}
+
+// requiredProfile returns the capability profile required for the policies of a repository, empty when none is.
+// Patterns match the repository name or any of its leading path components, 'ghcr.io' matches every repository
+// of the registry.
+func (c *Client) requiredProfile(ref reference.Named) string {
+	parts := strings.Split(ref.Name(), "/")
+
+	for _, required := range c.config.RequiredProfiles {
+		for i := len(parts); i > 0; i-- {
+			if ok, _ := path.Match(required.Pattern, strings.Join(parts[:i], "/")); ok {
+				return required.Profile
+			}
+		}
+	}
+
+	return ""
+}
+
+// satisfiesProfile reports whether images built with profile may be stored in repositories requiring required,
+// that is when profile is the required one or extends it.
+func (c *Client) satisfiesProfile(profile, required string) bool {
+	if profile == "" {
+		return false
+	}
+
+	lineage, err := c.profileLineage(profile)
+	if err != nil {
+		return profile == required
+	}
+
+	return slices.Contains(lineage, required)
+}
+
+// buildProfile returns the capability profile of a build, the one given or the one required for the first tag,
+// nil when there is none. Every tag must accept the profile.
+func (c *Client) buildProfile(opts *BuildOptions, tags []reference.Named) (*capabilityProfile, error) {
+	name := opts.CapabilityProfile
+
+	for _, tag := range tags {
+		required := c.requiredProfile(tag)
+		if required == "" {
+			continue
+		}
+
+		if name == "" {
+			name = required
+		}
+
+		if !c.satisfiesProfile(name, required) {
+			return nil, perr.ErrProfileViolated.WithMessage(
+				"[%s] requires capability profile [%s], the build uses [%s]", tag.String(), required, name)
+		}
+	}
+
+	if name == "" {
+		return nil, nil
+	}
+
+	return c.resolveProfile(name, opts.CapabilitiesJSONFile)
+}
+
+// explain reports the calls to built-ins denied by the profile, it returns err when there are none.
+func (p *capabilityProfile) explain(err error) error {
+	var undefined *runtime.UndefinedFunctionsError
+	if p == nil || !errors.As(err, &undefined) {
+		return err
+	}
+
+	calls := []string{}
+
+	for _, fn := range undefined.Functions {
+		if slices.Contains(p.Denied, fn.Name) {
+			calls = append(calls, fn.Name+" at "+strings.Join(fn.Locations, ", "))
+		}
+	}
+
+	if len(calls) == 0 {
+		return err
+	}
+
+	return errors.WithMessagef(undefined.Err,
+		"capability profile [%s] denies the built-in functions %s", p.Name, strings.Join(calls, "; "))
+}
+
+// checkPushProfile refuses to push an image to a repository requiring a capability profile when the bundle of the
+// image does not compile with the capabilities of the profile. The profile recorded on the image is not trusted,
+// annotations can be given to any build.
+func (c *Client) checkPushProfile(ctx context.Context, ociClient *oci.Oci, ref string, descriptor v1.Descriptor) error {
+	named, err := reference.ParseNamed(ref)
+	if err != nil {
+		return perr.ErrPushFailed.WithError(err)
+	}
+
+	required := c.requiredProfile(named)
+	if required == "" {
+		return nil
+	}
+
+	profile, err := c.resolveProfile(required, "")
+	if err != nil {
+		return perr.ErrPushFailed.WithError(err)
+	}
+
+	annotations, err := getAnnotations(&descriptor, ociClient)
+	if err != nil {
+		return perr.ErrPushFailed.WithError(err)
+	}
+
+	loadedBundle, err := c.loadBundle(ctx, ociClient, descriptor)
+	if err != nil {
+		return perr.ErrPushFailed.WithError(err)
+	}
+
+	regoVersion := runtime.RegoVersionFromString(annotations["rego.version"]).ToAstRegoVersion()
+
+	modules, stubs, err := runtime.BundleModules(loadedBundle, regoVersion)
+	if err != nil {
+		return perr.ErrPushFailed.WithError(err)
+	}
+
+	errs, err := runtime.CheckCapabilities(modules, stubs, profile.Capabilities)
+	if err != nil {
+		return perr.ErrPushFailed.WithError(err)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return perr.ErrProfileViolated.WithMessage(
+		"[%s] requires capability profile [%s], the image does not compile with it: %s",
+		ref, required, strings.Join(runtime.BriefErrors(errs), "; "))
+}
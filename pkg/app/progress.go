@@ -0,0 +1,21 @@
+package app
+
+import (
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const shortDigestLength = 12
+
+// transferProgress reports the transfer of a blob through the UI, labelled with its short digest.
+func (c *PolicyApp) transferProgress(desc v1.Descriptor) Progress {
+	return c.UI.Progress(shortDigest(desc), desc.Size)
+}
+
+func shortDigest(desc v1.Descriptor) string {
+	encoded := desc.Digest.Encoded()
+	if len(encoded) > shortDigestLength {
+		return encoded[:shortDigestLength]
+	}
+
+	return encoded
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"context"
+
+	"github.com/opcr-io/policy/internal/runtime"
+)
+
+// startProviders starts the builtin providers of the config and registers the builtins they declare,
+// the returned func stops them.
+func (c *Client) startProviders(ctx context.Context) (func(), error) {
+	if len(c.config.BuiltinProviders) == 0 {
+		return func() {}, nil
+	}
+
+	opaRuntime, err := runtime.New(c.logger.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	providers := []*runtime.Provider{}
+
+	stop := func() {
+		for _, p := range providers {
+			if err := p.Close(); err != nil {
+				c.logger.Warn().Err(err).Str("provider", p.Name()).Msg("Builtin provider exited with an error.")
+			}
+		}
+	}
+
+	for _, cfg := range c.config.BuiltinProviders {
+		p, err := runtime.StartProvider(ctx, c.logger, &runtime.ProviderConfig{
+			Name:    cfg.Name,
+			Command: cfg.Command,
+			Args:    cfg.Args,
+			Env:     cfg.Env,
+		})
+		if err != nil {
+			stop()
+			return nil, err
+		}
+
+		providers = append(providers, p)
+		opaRuntime.AddProvider(p)
+	}
+
+	opaRuntime.RegisterBuiltins()
+
+	return stop, nil
+}
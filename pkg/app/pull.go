@@ -1,68 +1,47 @@
 package app
 
 import (
-	"net/http"
+	"context"
 
-	"github.com/containerd/containerd/v2/core/remotes/docker"
-	"github.com/opcr-io/policy/internal/oci"
 	"github.com/opcr-io/policy/internal/parser"
-	"github.com/pkg/errors"
+	"github.com/opcr-io/policy/pkg/errors"
 )
 
 func (c *PolicyApp) Pull(userRef string) error {
 	defer c.Cancel()
 
-	ref, err := parser.CalculateRef(userRef, c.Configuration.DefaultDomain)
-	if err != nil {
-		return err
-	}
-
 	c.UI.Normal().
 		WithStringValue("ref", userRef).
 		Msg("Pulling.")
 
-	ociClient, err := oci.NewOCI(c.Context, c.Logger, c.getHosts, c.Configuration.PoliciesRoot())
+	result, err := c.client().Pull(c.Context, userRef)
 	if err != nil {
 		return err
 	}
 
-	digest, err := ociClient.Pull(ref)
-	if err != nil {
-		return errors.Wrap(err, "oras pull failed")
-	}
-
 	c.UI.Normal().
-		WithStringValue("digest", digest.String()).
-		Msgf("Pulled ref [%s].", ref)
+		WithStringValue("digest", result.Digest).
+		Msgf("Pulled ref [%s].", result.Reference)
 
-	return nil
+	return c.Output.Print(result)
 }
 
-func (c *PolicyApp) getHosts(server string) ([]docker.RegistryHost, error) {
-	client := &http.Client{Transport: c.TransportWithTrustedCAs()}
+// Pull copies a policy image and its tag from the registry into the local store.
+func (c *Client) Pull(ctx context.Context, userRef string) (*ImageReference, error) {
+	ref, err := parser.CalculateRef(userRef, c.config.DefaultDomain)
+	if err != nil {
+		return nil, err
+	}
 
-	scheme := "https"
-	if c.Configuration.Plaintext {
-		scheme = "http"
+	ociClient, err := c.newOCI(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return []docker.RegistryHost{
-		{
-			Host:         server,
-			Scheme:       scheme,
-			Capabilities: docker.HostCapabilityPull | docker.HostCapabilityResolve | docker.HostCapabilityPush,
-			Client:       client,
-			Path:         "/v2",
-			Authorizer: docker.NewDockerAuthorizer(
-				docker.WithAuthClient(client),
-				docker.WithAuthCreds(func(s string) (string, string, error) {
-					creds, err := c.Configuration.CredentialsStore.Get(s)
-					if err != nil || (creds.Username == "" && creds.Password == "") {
-						return " ", " ", nil //nolint:nilerr
-					}
+	digest, err := ociClient.Pull(ref)
+	if err != nil {
+		return nil, errors.ErrPullFailed.WithRemoteError(err)
+	}
 
-					return creds.Username, creds.Password, nil
-				})),
-		},
-	}, nil
+	return &ImageReference{Reference: ref, Digest: digest.String()}, nil
 }
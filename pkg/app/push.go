@@ -1,46 +1,81 @@
 package app
 
 import (
-	"github.com/opcr-io/policy/internal/oci"
+	"context"
+	"slices"
+
 	"github.com/opcr-io/policy/internal/parser"
 	"github.com/opcr-io/policy/pkg/errors"
 )
 
-func (c *PolicyApp) Push(userRef string) error {
+// PushOptions describes the upload of a local policy image to its registry.
+type PushOptions struct {
+	Reference string
+	// MountFrom lists repositories in the same registry to mount existing blobs from instead of uploading them,
+	// the mount_from repositories of the config are added.
+	MountFrom []string
+}
+
+func (c *PolicyApp) Push(userRef string, mountFrom []string) error {
 	defer c.Cancel()
 
-	ref, err := parser.CalculateRef(userRef, c.Configuration.DefaultDomain)
+	result, err := c.client().Push(c.Context, &PushOptions{Reference: userRef, MountFrom: mountFrom})
+	if err != nil {
+		return err
+	}
+
+	c.UI.Normal().
+		WithStringValue("digest", result.Digest).
+		Msgf("Pushed ref [%s].", result.Reference)
+
+	return c.Output.Print(result)
+}
+
+// Push uploads a policy image from the local store to the registry of its reference.
+func (c *Client) Push(ctx context.Context, opts *PushOptions) (*ImageReference, error) {
+	ref, err := parser.CalculateRef(opts.Reference, c.config.DefaultDomain)
 	if err != nil {
-		return errors.ErrPushFailed.WithError(err)
+		return nil, errors.ErrPushFailed.WithError(err)
 	}
 
-	ociClient, err := oci.NewOCI(c.Context, c.Logger, c.getHosts, c.Configuration.PoliciesRoot())
+	ociClient, err := c.newOCI(ctx)
 	if err != nil {
-		return errors.ErrPushFailed.WithError(err)
+		return nil, errors.ErrPushFailed.WithError(err)
 	}
 
 	refs, err := ociClient.ListReferences()
 	if err != nil {
-		return errors.ErrPushFailed.WithError(err)
+		return nil, errors.ErrPushFailed.WithError(err)
 	}
 
 	refDescriptor, ok := refs[ref]
 	if !ok {
-		return errors.ErrNotFound.WithMessage("policy [%s] not in the local store", ref)
+		return nil, errors.ErrNotFound.WithMessage("policy [%s] not in the local store", ref)
 	}
 
-	c.UI.Normal().
-		WithStringValue("digest", refDescriptor.Digest.String()).
-		Msgf("Resolved ref [%s].", ref)
+	c.logger.Debug().Str("digest", refDescriptor.Digest.String()).Msgf("Resolved ref [%s].", ref)
 
-	digest, err := ociClient.Push(ref)
-	if err != nil {
-		return errors.ErrPushFailed.WithError(err)
+	if err := c.checkPushProfile(ctx, ociClient, ref, refDescriptor); err != nil {
+		return nil, err
 	}
 
-	c.UI.Normal().
-		WithStringValue("digest", digest.String()).
-		Msgf("Pushed ref [%s].", ref)
+	sources := []string{}
+
+	for _, from := range slices.Concat(opts.MountFrom, c.config.MountFrom) {
+		source, err := parser.CalculateRef(from, c.config.DefaultDomain)
+		if err != nil {
+			return nil, errors.ErrPushFailed.WithError(err)
+		}
+
+		if !slices.Contains(sources, source) {
+			sources = append(sources, source)
+		}
+	}
+
+	digest, err := ociClient.Push(ref, sources)
+	if err != nil {
+		return nil, errors.ErrPushFailed.WithRemoteError(err)
+	}
 
-	return nil
+	return &ImageReference{Reference: ref, Digest: digest.String()}, nil
 }
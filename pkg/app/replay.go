@@ -0,0 +1,354 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/opcr-io/policy/internal/runtime"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/pkg/errors"
+)
+
+// ReplayOptions describes the evaluation of recorded inputs against two policy images.
+type ReplayOptions struct {
+	// From and To are the policy images compared, pulled when they are not in the local store.
+	From  string
+	To    string
+	Query string
+	// Inputs is a directory of JSON input documents, or a file of newline-delimited inputs or OPA decision log events.
+	Inputs string
+	// ExpectChanges are patterns, as for path.Match, of the input IDs whose decisions are expected to change.
+	ExpectChanges []string
+	// Mocks answers the calls to the stub builtins of the policies, which return null without.
+	Mocks *Mocks
+}
+
+// replayInput is an input document of a replay, identified by its file or decision ID.
+type replayInput struct {
+	ID    string
+	Input any
+}
+
+func (c *PolicyApp) Replay(opts *ReplayOptions) error {
+	defer c.Cancel()
+
+	report, err := c.client().Replay(c.Context, opts)
+	if err != nil {
+		return err
+	}
+
+	c.reportUnmocked(opts.Mocks)
+
+	if c.Output.Structured() {
+		if err := c.Output.Print(report); err != nil {
+			return err
+		}
+	} else {
+		for i := range report.Changes {
+			change := &report.Changes[i]
+
+			msg := c.UI.Normal()
+			if !change.Expected {
+				msg = c.UI.Exclamation()
+			}
+
+			msg.WithStringValue("input", displayValue(change.Input)).
+				WithStringValue("from", decisionDisplay(change.From, change.FromError)).
+				WithStringValue("to", decisionDisplay(change.To, change.ToError)).
+				Msgf("Decision changed for [%s].", change.ID)
+		}
+
+		c.UI.Normal().Msgf("Replayed %d inputs, %d decisions changed.", report.Inputs, len(report.Changes))
+	}
+
+	if report.Unexpected > 0 {
+		return errors.Errorf("%d decisions changed unexpectedly", report.Unexpected)
+	}
+
+	return nil
+}
+
+// Replay evaluates a query for every input against two policy images and reports the decisions that differ.
+func (c *Client) Replay(ctx context.Context, opts *ReplayOptions) (*ReplayReport, error) {
+	inputs, err := readReplayInputs(opts.Inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	stop, err := c.startProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+
+	from, err := c.prepareQuery(ctx, opts.From, opts.Query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to prepare [%s]", opts.From)
+	}
+
+	to, err := c.prepareQuery(ctx, opts.To, opts.Query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to prepare [%s]", opts.To)
+	}
+
+	ctx = runtime.WithMocks(ctx, opts.Mocks)
+
+	report := &ReplayReport{
+		From:    opts.From,
+		To:      opts.To,
+		Query:   opts.Query,
+		Inputs:  len(inputs),
+		Changes: []DecisionChange{},
+	}
+
+	for _, input := range inputs {
+		fromValue, fromErr := evalDecision(ctx, from, input.Input)
+		toValue, toErr := evalDecision(ctx, to, input.Input)
+
+		if equalValues(fromValue, toValue) && fromErr == toErr {
+			continue
+		}
+
+		change := DecisionChange{
+			ID:        input.ID,
+			Input:     input.Input,
+			From:      fromValue,
+			To:        toValue,
+			FromError: fromErr,
+			ToError:   toErr,
+			Expected:  expectedChange(opts.ExpectChanges, input.ID),
+		}
+
+		if !change.Expected {
+			report.Unexpected++
+		}
+
+		report.Changes = append(report.Changes, change)
+	}
+
+	return report, nil
+}
+
+// prepareQuery activates the bundle of a policy image and prepares a query for evaluation against it.
+func (c *Client) prepareQuery(ctx context.Context, ref, query string) (*rego.PreparedEvalQuery, error) {
+	ociClient, err := c.newOCI(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptor, err := c.localDescriptor(ctx, ociClient, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	loadedBundle, err := c.loadBundle(ctx, ociClient, descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	store, compiler, err := c.loadStore(ctx, loadedBundle)
+	if err != nil {
+		return nil, err
+	}
+
+	prepared, err := rego.New(
+		rego.Query(query),
+		rego.Store(store),
+		rego.Compiler(compiler),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &prepared, nil
+}
+
+// evalDecision returns the decision of a query for an input: the value of a single expression, the whole result
+// set otherwise, nil when undefined. Evaluation errors are returned as a message.
+func evalDecision(ctx context.Context, query *rego.PreparedEvalQuery, input any) (any, string) {
+	rs, err := query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return nil, err.Error()
+	}
+
+	var decision any
+
+	switch {
+	case len(rs) == 0:
+		return nil, ""
+	case len(rs) == 1 && len(rs[0].Expressions) == 1 && len(rs[0].Bindings) == 0:
+		decision = rs[0].Expressions[0].Value
+	default:
+		decision = rs
+	}
+
+	return normalize(decision), ""
+}
+
+func expectedChange(patterns []string, id string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, id); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func decisionDisplay(decision any, errMsg string) string {
+	switch {
+	case errMsg != "":
+		return "error: " + errMsg
+	case decision == nil:
+		return "undefined"
+	default:
+		return displayValue(decision)
+	}
+}
+
+// readReplayInputs reads the inputs of a replay. Every JSON file below a directory is an input, identified by its
+// path relative to the directory. A file holds newline-delimited inputs identified by their line, or OPA decision
+// log events, alone or in arrays, identified by their decision ID; events without input are skipped.
+func readReplayInputs(inputsPath string) ([]replayInput, error) {
+	info, err := os.Stat(inputsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return readInputStream(inputsPath)
+	}
+
+	files := []string{}
+
+	err = filepath.WalkDir(inputsPath, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() && strings.HasSuffix(file, ".json") {
+			files = append(files, file)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read inputs [%s]", inputsPath)
+	}
+
+	sort.Strings(files)
+
+	inputs := make([]replayInput, 0, len(files))
+
+	for _, file := range files {
+		buf, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var doc any
+		if err := json.Unmarshal(buf, &doc); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse input file [%s]", file)
+		}
+
+		rel, err := filepath.Rel(inputsPath, file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, input := range decisionInputs(doc) {
+			if input.ID == "" {
+				input.ID = filepath.ToSlash(rel)
+			}
+
+			inputs = append(inputs, input)
+		}
+	}
+
+	return inputs, nil
+}
+
+func readInputStream(file string) ([]replayInput, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	inputs := []replayInput{}
+	decoder := json.NewDecoder(bufio.NewReader(f))
+
+	for line := 1; ; line++ {
+		var doc any
+
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse input %d of [%s]", line, file)
+		}
+
+		for _, input := range decisionInputs(doc) {
+			if input.ID == "" {
+				input.ID = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+			}
+
+			inputs = append(inputs, input)
+		}
+	}
+
+	return inputs, nil
+}
+
+// decisionInputs returns the inputs of a document. The events of a decision log, one event or an array of events
+// as OPA uploads them, give their inputs with their decision IDs, events recorded without input are skipped. Other
+// documents are an input themselves.
+func decisionInputs(doc any) []replayInput {
+	if events, ok := doc.([]any); ok && isDecisionLog(events) {
+		inputs := []replayInput{}
+
+		for _, event := range events {
+			inputs = append(inputs, decisionInputs(event)...)
+		}
+
+		return inputs
+	}
+
+	event, _ := doc.(map[string]any)
+
+	id, ok := event["decision_id"].(string)
+	if !ok {
+		return []replayInput{{Input: doc}}
+	}
+
+	input, ok := event["input"]
+	if !ok {
+		return nil
+	}
+
+	return []replayInput{{ID: id, Input: input}}
+}
+
+// isDecisionLog reports whether every document of an array is an OPA decision log event, an object with a decision ID.
+func isDecisionLog(docs []any) bool {
+	for _, doc := range docs {
+		event, _ := doc.(map[string]any)
+		if _, ok := event["decision_id"].(string); !ok {
+			return false
+		}
+	}
+
+	return len(docs) > 0
+}
@@ -0,0 +1,147 @@
+package app
+
+import (
+	"github.com/opcr-io/policy/internal/runtime"
+
+	"github.com/open-policy-agent/opa/v1/cover"
+)
+
+// ImageReference identifies a local or remote image by reference and manifest digest.
+type ImageReference struct {
+	Reference string `json:"reference"`
+	Digest    string `json:"digest"`
+}
+
+// Image is a row of the local image list.
+type Image struct {
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+	ImageID    string `json:"image_id"`
+	Digest     string `json:"digest"`
+	Created    string `json:"created"`
+	Size       int64  `json:"size"`
+
+	display string // repository column of the images table.
+}
+
+// ImageDetails describes a local image descriptor and its annotations.
+type ImageDetails struct {
+	Reference   string            `json:"reference"`
+	MediaType   string            `json:"media_type"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+	// Entrypoints are the entrypoints recorded by the build, given and annotated.
+	Entrypoints []string `json:"entrypoints,omitempty"`
+	// Schemas are the references of the schemas stored with images built with schemas.
+	Schemas []string `json:"schemas,omitempty"`
+	// CapabilityProfile is the capability profile the image was built with.
+	CapabilityProfile string `json:"capability_profile,omitempty"`
+	// Docs is the documentation summary of images built with docs.
+	Docs []runtime.PackageSummary `json:"docs,omitempty"`
+}
+
+// SavedBundle describes a bundle tarball written by save.
+type SavedBundle struct {
+	Reference string `json:"reference"`
+	Digest    string `json:"digest"`
+	File      string `json:"file"`
+}
+
+// UnpackResult lists the files written by unpack, relative to the directory.
+type UnpackResult struct {
+	Reference string   `json:"reference"`
+	Digest    string   `json:"digest"`
+	Dir       string   `json:"dir"`
+	Files     []string `json:"files"`
+	Verified  bool     `json:"verified"`
+}
+
+// Template describes a template that can be applied.
+type Template struct {
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+}
+
+// BuiltinsScan lists the undefined functions found in policy sources and the manifest declaring them.
+type BuiltinsScan struct {
+	Functions []runtime.UndefinedFunction `json:"functions"`
+	Manifest  string                      `json:"manifest,omitempty"`
+	Updated   bool                        `json:"updated"`
+}
+
+// TestReport summarizes a run of the Rego tests of policy sources.
+type TestReport struct {
+	Passed  int        `json:"passed"`
+	Failed  int        `json:"failed"`
+	Errored int        `json:"errored"`
+	Skipped int        `json:"skipped"`
+	Tests   []TestCase `json:"tests"`
+	// Coverage is the line coverage of the modules, in the JSON format of OPA, when asked for.
+	Coverage *cover.Report `json:"coverage,omitempty"`
+}
+
+// TestCase is the outcome of a Rego test: pass, fail, error or skip.
+type TestCase struct {
+	Package  string `json:"package"`
+	Name     string `json:"name"`
+	Location string `json:"location"`
+	Outcome  string `json:"outcome"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+	Output   string `json:"output,omitempty"`
+}
+
+// ImageDiff lists the differences between the bundles and the annotations of two policy images.
+type ImageDiff struct {
+	From        string         `json:"from"`
+	To          string         `json:"to"`
+	Modules     []ModuleChange `json:"modules"`
+	Data        []ValueChange  `json:"data"`
+	Manifest    []ValueChange  `json:"manifest"`
+	Annotations []ValueChange  `json:"annotations"`
+}
+
+// Empty reports whether both images have the same modules, data, manifest and annotations.
+func (d *ImageDiff) Empty() bool {
+	return len(d.Modules) == 0 && len(d.Data) == 0 && len(d.Manifest) == 0 && len(d.Annotations) == 0
+}
+
+// ModuleChange is a Rego module added, removed or modified between two images, with its unified diff.
+type ModuleChange struct {
+	File   string `json:"file"`
+	Change string `json:"change"`
+	Diff   string `json:"diff"`
+}
+
+// ValueChange is a value added, removed or modified between two images, at a JSON path of the data documents,
+// a field of the manifest or an annotation key.
+type ValueChange struct {
+	Path   string `json:"path"`
+	Change string `json:"change"`
+	From   any    `json:"from,omitempty"`
+	To     any    `json:"to,omitempty"`
+}
+
+// ReplayReport lists the decisions that differ between two policy images for the same inputs.
+type ReplayReport struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Query  string `json:"query"`
+	Inputs int    `json:"inputs"`
+	// Unexpected counts the changes that were not expected.
+	Unexpected int              `json:"unexpected"`
+	Changes    []DecisionChange `json:"changes"`
+}
+
+// DecisionChange is a decision that differs between two policy images, a nil decision is undefined.
+type DecisionChange struct {
+	ID        string `json:"id"`
+	Input     any    `json:"input"`
+	From      any    `json:"from"`
+	To        any    `json:"to"`
+	FromError string `json:"from_error,omitempty"`
+	ToError   string `json:"to_error,omitempty"`
+	Expected  bool   `json:"expected"`
+}
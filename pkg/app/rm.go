@@ -31,11 +31,10 @@ func (c *PolicyApp) Rm(existingRef string, force bool) error {
 	}
 
 	if !confirmation {
-		c.UI.Exclamation().Msg("Operation canceled by user.")
-		return nil
+		return errors.ErrUserCancelled
 	}
 
-	ociClient, err := oci.NewOCI(c.Context, c.Logger, c.getHosts, c.Configuration.PoliciesRoot())
+	ociClient, err := c.client().newOCI(c.Context)
 	if err != nil {
 		return err
 	}
@@ -63,13 +62,19 @@ func (c *PolicyApp) Rm(existingRef string, force bool) error {
 	}
 
 	// Reload ociClient with refreshed index to update reference list.
-	ociClient, err = oci.NewOCI(c.Context, c.Logger, c.getHosts, c.Configuration.PoliciesRoot())
+	ociClient, err = c.client().newOCI(c.Context)
 	if err != nil {
 		return err
 	}
 
+	removed := &ImageReference{Reference: existingRefParsed, Digest: ref.Digest.String()}
+
 	if ref.MediaType != oci.MediaTypeImageLayer {
-		return c.removeBasedOnManifest(ociClient, &ref, existingRefParsed)
+		if err := c.removeBasedOnManifest(ociClient, &ref, existingRefParsed); err != nil {
+			return err
+		}
+
+		return c.Output.Print(removed)
 	}
 
 	err = c.removeBasedOnTarball(ociClient, &ref, existingRefs, existingRefParsed)
@@ -81,7 +86,7 @@ func (c *PolicyApp) Rm(existingRef string, force bool) error {
 		WithStringValue("reference", existingRef).
 		Msg("Removed reference.")
 
-	return nil
+	return c.Output.Print(removed)
 }
 
 type index struct {
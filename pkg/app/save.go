@@ -1,8 +1,7 @@
 package app
 
 import (
-	"bytes"
-	"errors"
+	"context"
 	"io"
 	"os"
 
@@ -10,58 +9,80 @@ import (
 	"github.com/opcr-io/policy/internal/parser"
 	perr "github.com/opcr-io/policy/pkg/errors"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
 )
 
+// SaveOptions describes the export of a policy bundle from the local store.
+type SaveOptions struct {
+	Reference string
+	// Output receives the bundle tarball.
+	Output io.Writer
+}
+
 func (c *PolicyApp) Save(userRef, outputFilePath string) error {
 	defer c.Cancel()
 
-	var outputFile *os.File
+	// stdout already carries the bundle itself.
+	if outputFilePath == "-" {
+		_, err := c.client().Save(c.Context, &SaveOptions{Reference: userRef, Output: os.Stdout})
+		return err
+	}
 
-	ref, err := parser.CalculateRef(userRef, c.Configuration.DefaultDomain)
+	outputFile, err := os.Create(outputFilePath)
 	if err != nil {
-		return perr.ErrSaveFailed.WithError(err)
+		return perr.ErrSaveFailed.WithMessage("failed to create output file [%s]", outputFilePath)
 	}
 
-	ociClient, err := oci.NewOCI(c.Context, c.Logger, c.getHosts, c.Configuration.PoliciesRoot())
-	if err != nil {
-		return perr.ErrSaveFailed.WithError(err)
+	result, err := c.client().Save(c.Context, &SaveOptions{Reference: userRef, Output: outputFile})
+
+	if closeErr := outputFile.Close(); closeErr != nil {
+		c.UI.Problem().WithErr(closeErr).Msg("Failed to close policy bundle tarball.")
 	}
 
-	// if the reference descriptor is the manifest get the tarball descriptor information from the manifest layers.
-	refDescriptor, err := c.getRefDescriptor(ociClient, ref)
 	if err != nil {
-		return perr.ErrSaveFailed.WithError(err)
+		if removeErr := os.Remove(outputFilePath); removeErr != nil {
+			c.UI.Problem().WithErr(removeErr).Msg("Failed to remove incomplete policy bundle tarball.")
+		}
+
+		return err
 	}
 
-	if outputFilePath == "-" {
-		outputFile = os.Stdout
-	} else {
-		c.UI.Normal().
-			WithStringValue("digest", refDescriptor.Digest.String()).
-			Msgf("Resolved ref [%s].", ref)
+	c.UI.Normal().
+		WithStringValue("digest", result.Digest).
+		Msgf("Saved ref [%s].", result.Reference)
 
-		outputFile, err = os.Create(outputFilePath)
-		if err != nil {
-			return perr.ErrSaveFailed.WithMessage("failed to create output file [%s]", outputFilePath)
-		}
+	result.File = outputFilePath
 
-		defer func() {
-			err := outputFile.Close()
-			if err != nil {
-				c.UI.Problem().WithErr(err).Msg("Failed to close policy bundle tarball.")
-			}
-		}()
+	return c.Output.Print(result)
+}
+
+// Save writes the bundle tarball of a policy image in the local store to opts.Output.
+func (c *Client) Save(ctx context.Context, opts *SaveOptions) (*SavedBundle, error) {
+	ref, err := parser.CalculateRef(opts.Reference, c.config.DefaultDomain)
+	if err != nil {
+		return nil, perr.ErrSaveFailed.WithError(err)
 	}
 
-	err = c.writePolicy(ociClient, refDescriptor, outputFile)
+	ociClient, err := c.newOCI(ctx)
 	if err != nil {
-		return perr.ErrSaveFailed.WithError(err)
+		return nil, perr.ErrSaveFailed.WithError(err)
 	}
 
-	return nil
+	// if the reference descriptor is the manifest get the tarball descriptor information from the manifest layers.
+	refDescriptor, err := c.getRefDescriptor(ctx, ociClient, ref)
+	if err != nil {
+		return nil, perr.ErrSaveFailed.WithError(err)
+	}
+
+	err = c.writePolicy(ctx, ociClient, refDescriptor, opts.Output)
+	if err != nil {
+		return nil, perr.ErrSaveFailed.WithError(err)
+	}
+
+	return &SavedBundle{Reference: ref, Digest: refDescriptor.Digest.String()}, nil
 }
 
-func (c *PolicyApp) getRefDescriptor(ociClient *oci.Oci, ref string) (*v1.Descriptor, error) {
+func (c *Client) getRefDescriptor(ctx context.Context, ociClient *oci.Oci, ref string) (*v1.Descriptor, error) {
 	refs, err := ociClient.ListReferences()
 	if err != nil {
 		return nil, err
@@ -73,7 +94,7 @@ func (c *PolicyApp) getRefDescriptor(ociClient *oci.Oci, ref string) (*v1.Descri
 	}
 
 	if refDescriptor.MediaType == v1.MediaTypeImageManifest {
-		bundleDescriptor, _, err := ociClient.GetTarballAndConfigLayerDescriptor(c.Context, &refDescriptor)
+		bundleDescriptor, _, err := ociClient.GetTarballAndConfigLayerDescriptor(ctx, &refDescriptor)
 		if err != nil {
 			return nil, err
 		}
@@ -84,8 +105,8 @@ func (c *PolicyApp) getRefDescriptor(ociClient *oci.Oci, ref string) (*v1.Descri
 	return &refDescriptor, nil
 }
 
-func (c *PolicyApp) writePolicy(ociStore *oci.Oci, refDescriptor *v1.Descriptor, outputFile io.Writer) error {
-	reader, err := ociStore.GetStore().Fetch(c.Context, *refDescriptor)
+func (c *Client) writePolicy(ctx context.Context, ociStore *oci.Oci, refDescriptor *v1.Descriptor, output io.Writer) error {
+	reader, err := ociStore.GetStore().Fetch(ctx, *refDescriptor)
 	if err != nil {
 		return err
 	}
@@ -93,19 +114,24 @@ func (c *PolicyApp) writePolicy(ociStore *oci.Oci, refDescriptor *v1.Descriptor,
 	defer func() {
 		err := reader.Close()
 		if err != nil {
-			c.UI.Problem().WithErr(err).Msg("Failed to close OCI policy reader.")
+			c.logger.Warn().Err(err).Msg("Failed to close OCI policy reader.")
 		}
 	}()
 
-	buf := new(bytes.Buffer)
+	progress := c.track(*refDescriptor)
+
+	// stream the blob to the output while verifying its digest, the bundle is never held in memory.
+	verifier := content.NewVerifyReader(oci.NewProgressReader(reader, progress), *refDescriptor)
 
-	if _, err := buf.ReadFrom(reader); err != nil && !errors.Is(err, io.EOF) {
+	if _, err := io.Copy(output, verifier); err != nil {
 		return err
 	}
 
-	if _, err := outputFile.Write(buf.Bytes()); err != nil {
+	if err := verifier.Verify(); err != nil {
 		return err
 	}
 
+	progress.Done()
+
 	return nil
 }
@@ -3,7 +3,6 @@ package app
 import (
 	"strings"
 
-	"github.com/opcr-io/policy/internal/oci"
 	"github.com/opcr-io/policy/internal/parser"
 	"github.com/pkg/errors"
 )
@@ -11,7 +10,7 @@ import (
 func (c *PolicyApp) Tag(existingRef, newRef string) error {
 	defer c.Cancel()
 
-	ociClient, err := oci.NewOCI(c.Context, c.Logger, c.getHosts, c.Configuration.PoliciesRoot())
+	ociClient, err := c.client().newOCI(c.Context)
 	if err != nil {
 		return err
 	}
@@ -39,5 +38,10 @@ func (c *PolicyApp) Tag(existingRef, newRef string) error {
 		return err
 	}
 
-	return nil
+	desc, err := ociClient.GetStore().Resolve(c.Context, parsed)
+	if err != nil {
+		return err
+	}
+
+	return c.Output.Print(&ImageReference{Reference: parsed, Digest: desc.Digest.String()})
 }
@@ -38,20 +38,29 @@ func (c *PolicyApp) TemplatesList() error {
 		return templateInfos[i].name < templateInfos[j].name
 	})
 
-	data := [][]any{}
+	list := []Template{}
 
 	for _, tmplInfo := range templateInfos {
 		if tmplInfo.kind == "" {
 			continue
 		}
 
-		data = append(data, []any{
-			tmplInfo.name,
-			tmplInfo.kind,
-			tmplInfo.description,
+		list = append(list, Template{
+			Name:        tmplInfo.name,
+			Kind:        tmplInfo.kind,
+			Description: tmplInfo.description,
 		})
 	}
 
+	if c.Output.Structured() {
+		return c.Output.Print(list)
+	}
+
+	data := [][]any{}
+	for _, tmpl := range list {
+		data = append(data, []any{tmpl.Name, tmpl.Kind, tmpl.Description})
+	}
+
 	t := table.New(os.Stdout)
 	t.Header("Name", "Kind", "Description")
 	t.Bulk(data)
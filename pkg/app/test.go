@@ -0,0 +1,211 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/opcr-io/policy/internal/runtime"
+	perr "github.com/opcr-io/policy/pkg/errors"
+	"github.com/opcr-io/policy/pkg/table"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/cover"
+	"github.com/open-policy-agent/opa/v1/tester"
+	"github.com/pkg/errors"
+)
+
+// TestOptions describes a run of the Rego tests of policy sources.
+type TestOptions struct {
+	Paths                []string
+	Ignore               []string
+	CapabilitiesJSONFile string
+	RegoVersion          RegoVersion
+	// Run selects the tests to run by a regular expression on their package and name.
+	Run string
+	// Mocks answers the calls to stub builtins, which return null without.
+	Mocks *Mocks
+	// Schema is a JSON schema file for the input, or a directory of schemas, the modules are type-checked against.
+	Schema string
+	// Coverage reports the line coverage of the modules other than tests, it is implied by the other coverage options.
+	Coverage bool
+	// MinCoverage fails the run when the coverage, in percent, is below it.
+	MinCoverage float64
+	// CoverageFile is the file the coverage report is written to, in CoverageFormat: json (OPA), lcov or cobertura.
+	CoverageFile   string
+	CoverageFormat string
+	// CoverageTimestamp is recorded as the time of the run in Cobertura reports, the current time when zero.
+	CoverageTimestamp time.Time
+}
+
+func (o *TestOptions) coverage() bool {
+	return o.Coverage || o.MinCoverage > 0 || o.CoverageFile != ""
+}
+
+func (c *PolicyApp) Test(opts *TestOptions, verbose bool) error {
+	defer c.Cancel()
+
+	results, coverage, err := c.client().Test(c.Context, opts)
+	if err != nil {
+		return err
+	}
+
+	c.reportUnmocked(opts.Mocks)
+
+	report := testReport(results)
+	report.Coverage = coverage
+
+	if c.Output.Structured() {
+		if err := c.Output.Print(report); err != nil {
+			return err
+		}
+	} else {
+		ch := make(chan *tester.Result, len(results))
+		for _, result := range results {
+			ch <- result
+		}
+
+		close(ch)
+
+		reporter := tester.PrettyReporter{Output: os.Stdout, Verbose: verbose, FailureLine: true}
+		if err := reporter.Report(ch); err != nil {
+			return err
+		}
+
+		if coverage != nil {
+			c.reportCoverage(coverage)
+		}
+	}
+
+	if opts.CoverageFile != "" {
+		if err := writeCoverage(opts, coverage); err != nil {
+			return err
+		}
+	}
+
+	return report.check(opts.MinCoverage)
+}
+
+func (c *PolicyApp) reportCoverage(coverage *cover.Report) {
+	data := [][]any{}
+
+	for _, file := range slices.Sorted(maps.Keys(coverage.Files)) {
+		fr := coverage.Files[file]
+		data = append(data, []any{file, fr.CoveredLines, fr.NotCoveredLines, fmt.Sprintf("%.2f%%", fr.Coverage)})
+	}
+
+	t := table.New(os.Stdout)
+	t.Header("File", "Covered", "Not Covered", "Coverage")
+	t.Bulk(data)
+	t.Render()
+
+	c.UI.Normal().
+		WithStringValue("coverage", fmt.Sprintf("%.2f%%", coverage.Coverage)).
+		Msg("Test coverage.")
+}
+
+// check fails for failed tests and, with a minimum, for a coverage below it.
+func (r *TestReport) check(minCoverage float64) error {
+	if failed := r.Failed + r.Errored; failed > 0 {
+		return errors.Errorf("%d of %d tests failed", failed, len(r.Tests))
+	}
+
+	if r.Coverage != nil && r.Coverage.Coverage < minCoverage {
+		return errors.Errorf("test coverage %.2f%% is below the minimum of %.2f%%", r.Coverage.Coverage, minCoverage)
+	}
+
+	return nil
+}
+
+func writeCoverage(opts *TestOptions, coverage *cover.Report) error {
+	timestamp := opts.CoverageTimestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	buf := &bytes.Buffer{}
+	if err := runtime.WriteCoverage(buf, coverage, opts.CoverageFormat, timestamp); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(opts.CoverageFile, buf.Bytes(), 0o600); err != nil {
+		return errors.Wrapf(err, "failed to write [%s]", opts.CoverageFile)
+	}
+
+	return nil
+}
+
+// Test runs the Rego tests of policy sources, and reports the line coverage of the modules when asked to.
+func (c *Client) Test(ctx context.Context, opts *TestOptions) ([]*tester.Result, *cover.Report, error) {
+	stop, err := c.startProviders(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer stop()
+
+	schemas, err := loadSchemas(opts.Schema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results, coverage, err := runtime.Test(ctx, &runtime.TestParams{
+		CapabilitiesJSONFile: opts.CapabilitiesJSONFile,
+		Ignore:               opts.Ignore,
+		RegoVersion:          opts.RegoVersion,
+		Run:                  opts.Run,
+		Mocks:                opts.Mocks,
+		Schemas:              schemas,
+		Coverage:             opts.coverage(),
+	}, opts.Paths)
+	if err != nil {
+		var astErrs ast.Errors
+		if errors.As(err, &astErrs) {
+			return nil, nil, perr.ErrCompileFailed.WithError(err)
+		}
+
+		return nil, nil, err
+	}
+
+	return results, coverage, nil
+}
+
+func testReport(results []*tester.Result) *TestReport {
+	report := &TestReport{Tests: []TestCase{}}
+
+	for _, result := range results {
+		tc := TestCase{
+			Package:  result.Package,
+			Name:     result.Name,
+			Duration: result.Duration.String(),
+			Output:   string(result.Output),
+		}
+
+		if result.Location != nil {
+			tc.Location = result.Location.String()
+		}
+
+		switch {
+		case result.Error != nil:
+			tc.Outcome = "error"
+			tc.Error = result.Error.Error()
+			report.Errored++
+		case result.Skip:
+			tc.Outcome = "skip"
+			report.Skipped++
+		case result.Fail:
+			tc.Outcome = "fail"
+			report.Failed++
+		default:
+			tc.Outcome = "pass"
+			report.Passed++
+		}
+
+		report.Tests = append(report.Tests, tc)
+	}
+
+	return report
+}
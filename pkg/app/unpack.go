@@ -0,0 +1,112 @@
+package app
+
+import (
+	"context"
+	"io"
+
+	"github.com/opcr-io/policy/internal/parser"
+	"github.com/opcr-io/policy/internal/runtime"
+
+	"oras.land/oras-go/v2/content"
+
+	"github.com/pkg/errors"
+)
+
+// UnpackOptions describes the extraction of the bundle of a policy image into a directory.
+type UnpackOptions struct {
+	Reference string
+	// Dir receives the bundle files, it must be missing or empty.
+	Dir string
+	// PubKey verifies the bundle signatures before anything is written, when set.
+	PubKey             string
+	PubKeyID           string
+	Algorithm          string
+	Scope              string
+	ExcludeVerifyFiles []string
+}
+
+func (c *PolicyApp) Unpack(opts *UnpackOptions) error {
+	defer c.Cancel()
+
+	result, err := c.client().Unpack(c.Context, opts)
+	if err != nil {
+		return err
+	}
+
+	if result.Verified {
+		c.UI.Normal().Msg("Verified bundle signatures.")
+	}
+
+	c.UI.Normal().
+		WithStringValue("digest", result.Digest).
+		WithIntValue("files", int64(len(result.Files))).
+		Msgf("Unpacked ref [%s] to [%s].", result.Reference, result.Dir)
+
+	return c.Output.Print(result)
+}
+
+// Unpack writes the modules, data and manifest of the bundle of a policy image in the local store to opts.Dir.
+func (c *Client) Unpack(ctx context.Context, opts *UnpackOptions) (*UnpackResult, error) {
+	ref, err := parser.CalculateRef(opts.Reference, c.config.DefaultDomain)
+	if err != nil {
+		return nil, err
+	}
+
+	ociClient, err := c.newOCI(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// images tagged at the bundle tarball, as with v0.1, resolve to the tarball itself.
+	bundleDescriptor, err := c.getRefDescriptor(ctx, ociClient, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	// the blob is streamed from the store and checked against its digest as it is read, the bundle is never held
+	// in memory.
+	open := func() (io.ReadCloser, error) {
+		reader, err := ociClient.GetStore().Fetch(ctx, *bundleDescriptor)
+		if err != nil {
+			return nil, err
+		}
+
+		return &verifyReader{VerifyReader: content.NewVerifyReader(reader, *bundleDescriptor), Closer: reader}, nil
+	}
+
+	files, err := runtime.Unpack(open, opts.Dir, &runtime.UnpackParams{
+		PubKey:             opts.PubKey,
+		PubKeyID:           opts.PubKeyID,
+		Algorithm:          opts.Algorithm,
+		Scope:              opts.Scope,
+		ExcludeVerifyFiles: opts.ExcludeVerifyFiles,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &UnpackResult{
+		Reference: ref,
+		Digest:    bundleDescriptor.Digest.String(),
+		Dir:       opts.Dir,
+		Files:     files,
+		Verified:  opts.PubKey != "",
+	}, nil
+}
+
+// verifyReader reports a blob not matching its descriptor as an error of the read reaching its end.
+type verifyReader struct {
+	*content.VerifyReader
+	io.Closer
+}
+
+func (r *verifyReader) Read(p []byte) (int, error) {
+	n, err := r.VerifyReader.Read(p)
+	if errors.Is(err, io.EOF) {
+		if err := r.Verify(); err != nil {
+			return n, err
+		}
+	}
+
+	return n, err
+}
@@ -28,9 +28,45 @@ type Config struct {
 	Insecure         bool              `json:"insecure" yaml:"insecure"`
 	Plaintext        bool              `json:"plaintext" yaml:"plaintext"`
 	TokenDefaults    map[string]string `json:"token_defaults" yaml:"token_defaults"`
+	BuiltinProviders []BuiltinProvider `json:"builtin_providers" yaml:"builtin_providers"`
+	// CapabilityProfiles are the named capabilities policies can be built with.
+	CapabilityProfiles map[string]CapabilityProfile `json:"capability_profiles" yaml:"capability_profiles"`
+	// RequiredProfiles map registries and repositories to the capability profile their policies must be built with,
+	// the first matching pattern applies.
+	RequiredProfiles []RequiredProfile `json:"required_profiles" yaml:"required_profiles"`
+	// MountFrom lists repositories to mount existing blobs from by default, in addition to those given to a push.
+	// A repository only applies to pushes to its own registry.
+	MountFrom        []string          `json:"mount_from" yaml:"mount_from"`
 	CredentialsStore credentials.Store `json:"-"`
 }
 
+// BuiltinProvider is an external executable implementing custom builtins, started by the commands evaluating policies.
+type BuiltinProvider struct {
+	Name    string   `json:"name" yaml:"name"`
+	Command string   `json:"command" yaml:"command"`
+	Args    []string `json:"args" yaml:"args"`
+	// Env lists additional KEY=value environment variables of the provider.
+	Env []string `json:"env" yaml:"env"`
+}
+
+// CapabilityProfile restricts the built-in functions available to policies.
+type CapabilityProfile struct {
+	// Extends is the profile this one inherits the capabilities and deny patterns of.
+	Extends string `json:"extends" yaml:"extends"`
+	// Capabilities is a capabilities.json file replacing the inherited capabilities, those of this version of OPA
+	// when no profile sets one.
+	Capabilities string `json:"capabilities" yaml:"capabilities"`
+	// Deny lists the built-in functions removed from the capabilities, by name or glob pattern as 'net.*'.
+	Deny []string `json:"deny" yaml:"deny"`
+}
+
+// RequiredProfile requires a capability profile for the policies of the registries or repositories matching Pattern,
+// as 'ghcr.io' or 'ghcr.io/acme/prod-*'.
+type RequiredProfile struct {
+	Pattern string `json:"pattern" yaml:"pattern"`
+	Profile string `json:"profile" yaml:"profile"`
+}
+
 // Path is a string that points to a config file.
 type Path string
 
@@ -142,6 +178,10 @@ func (c *Config) PoliciesRoot() string {
 	return filepath.Join(c.FileStoreRoot, "policies-root")
 }
 
+func (c *Config) BuildCacheRoot() string {
+	return filepath.Join(c.FileStoreRoot, "build-cache")
+}
+
 func (c *Config) ReplHistoryFile() string {
 	return filepath.Join(c.FileStoreRoot, "repl_history")
 }
@@ -96,8 +96,6 @@ func (u *Message) Msg(message string) {
 	case success:
 		output = u.ui.Output()
 		message = color.GreenString(message)
-	case progress:
-		output = u.ui.Output()
 	case problem:
 		output = u.ui.Err()
 		message = color.RedString(message)
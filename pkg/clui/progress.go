@@ -0,0 +1,241 @@
+package clui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"golang.org/x/term"
+)
+
+const (
+	progressBarWidth    = 30
+	progressRedrawRate  = 100 * time.Millisecond
+	progressLogInterval = 5 * time.Second
+)
+
+// SetQuiet disables progress reporting.
+func (u *UI) SetQuiet(quiet bool) {
+	u.quiet = quiet
+}
+
+// Progress returns a bar that reports the transfer of total bytes of the named item, nil when quiet.
+// Progress is written to the error output, as bars with bytes, rate and ETA on a terminal
+// and as periodic log lines otherwise.
+func (u *UI) Progress(name string, total int64) *ProgressBar {
+	if u.quiet {
+		return nil
+	}
+
+	u.transfersOnce.Do(func() {
+		u.transfers = newTransfers(u.Err())
+	})
+
+	return u.transfers.add(name, total)
+}
+
+type barState int
+
+const (
+	barRunning barState = iota
+	barDone
+	barSkipped
+)
+
+// transfers renders the progress of all transfers running at the same time.
+type transfers struct {
+	mu       sync.Mutex
+	out      io.Writer
+	tty      bool
+	bars     []*ProgressBar
+	drawn    int
+	lastDraw time.Time
+}
+
+// ProgressBar reports the progress of a single transfer, a nil bar reports nothing.
+type ProgressBar struct {
+	t       *transfers
+	name    string
+	total   int64
+	current int64
+	started time.Time
+	ended   time.Time
+	logged  time.Time
+	state   barState
+}
+
+func newTransfers(out io.Writer) *transfers {
+	tty := false
+	if f, ok := out.(*os.File); ok {
+		tty = term.IsTerminal(int(f.Fd()))
+	}
+
+	return &transfers{out: out, tty: tty}
+}
+
+func (t *transfers) add(name string, total int64) *ProgressBar {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	b := &ProgressBar{t: t, name: name, total: total, started: now, logged: now}
+	t.bars = append(t.bars, b)
+
+	t.update(b, true)
+
+	return b
+}
+
+// Add records n more bytes as transferred.
+func (b *ProgressBar) Add(n int64) {
+	if b == nil {
+		return
+	}
+
+	b.t.mu.Lock()
+	defer b.t.mu.Unlock()
+
+	b.current += n
+	b.t.update(b, false)
+}
+
+// Done marks the transfer as completed.
+func (b *ProgressBar) Done() {
+	b.finish(barDone)
+}
+
+// Skip marks the transfer as not needed, e.g. because the content already exists.
+func (b *ProgressBar) Skip() {
+	b.finish(barSkipped)
+}
+
+func (b *ProgressBar) finish(state barState) {
+	if b == nil {
+		return
+	}
+
+	b.t.mu.Lock()
+	defer b.t.mu.Unlock()
+
+	if b.state != barRunning {
+		return
+	}
+
+	b.state = state
+	b.ended = time.Now()
+	b.t.update(b, true)
+}
+
+// update prints the progress of b, the caller must hold the lock.
+func (t *transfers) update(b *ProgressBar, force bool) {
+	now := time.Now()
+
+	if !t.tty {
+		if force || now.Sub(b.logged) >= progressLogInterval {
+			b.logged = now
+			fmt.Fprintln(t.out, b.line(now))
+		}
+
+		return
+	}
+
+	if !force && now.Sub(t.lastDraw) < progressRedrawRate {
+		return
+	}
+
+	// move the cursor back over the previously drawn bars and redraw them in place.
+	if t.drawn > 0 {
+		fmt.Fprintf(t.out, "\x1b[%dA", t.drawn)
+	}
+
+	running := 0
+
+	for _, b := range t.bars {
+		fmt.Fprintf(t.out, "\x1b[2K%s\n", b.render(now))
+
+		if b.state == barRunning {
+			running++
+		}
+	}
+
+	t.drawn = len(t.bars)
+	t.lastDraw = now
+
+	// once everything finished, later transfers start below the bars drawn so far.
+	if running == 0 {
+		t.bars = nil
+		t.drawn = 0
+	}
+}
+
+// render formats the bar for a terminal.
+func (b *ProgressBar) render(now time.Time) string {
+	switch b.state {
+	case barSkipped:
+		return fmt.Sprintf("%s  Already exists", b.name)
+	case barDone:
+		return fmt.Sprintf("%s  Done  %s in %s", b.name, formatBytes(b.current), b.ended.Sub(b.started).Round(time.Millisecond))
+	case barRunning:
+	}
+
+	filled := progressBarWidth
+	if b.total > 0 && b.current < b.total {
+		filled = int(b.current * progressBarWidth / b.total)
+	}
+
+	gauge := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		gauge += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+
+	return fmt.Sprintf("%s  [%s] %s/%s  %s/s  ETA %s", b.name, gauge, formatBytes(b.current), formatBytes(b.total), formatBytes(b.rate(now)), b.eta(now))
+}
+
+// line formats the progress as a plain log line.
+func (b *ProgressBar) line(now time.Time) string {
+	switch b.state {
+	case barSkipped:
+		return fmt.Sprintf("%s: already exists", b.name)
+	case barDone:
+		return fmt.Sprintf("%s: done %s in %s", b.name, formatBytes(b.current), b.ended.Sub(b.started).Round(time.Millisecond))
+	case barRunning:
+	}
+
+	percent := int64(0)
+	if b.total > 0 {
+		percent = b.current * 100 / b.total
+	}
+
+	return fmt.Sprintf("%s: %s/%s (%d%%) %s/s ETA %s", b.name, formatBytes(b.current), formatBytes(b.total), percent, formatBytes(b.rate(now)), b.eta(now))
+}
+
+// rate returns the average transfer rate in bytes per second.
+func (b *ProgressBar) rate(now time.Time) int64 {
+	elapsed := now.Sub(b.started).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+
+	return int64(float64(b.current) / elapsed)
+}
+
+func (b *ProgressBar) eta(now time.Time) string {
+	rate := b.rate(now)
+	if rate == 0 || b.total <= b.current {
+		return "-"
+	}
+
+	return (time.Duration((b.total-b.current)/rate) * time.Second).String()
+}
+
+func formatBytes(n int64) string {
+	if n < 0 {
+		n = 0
+	}
+
+	return strings.ReplaceAll(humanize.Bytes(uint64(n)), " ", "")
+}
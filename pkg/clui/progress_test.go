@@ -0,0 +1,122 @@
+package clui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressBarRender(t *testing.T) {
+	started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tcs := []struct {
+		name     string
+		bar      ProgressBar
+		now      time.Time
+		expected string
+	}{
+		{
+			name:     "started",
+			bar:      ProgressBar{name: "abc", total: 4000, started: started},
+			now:      started,
+			expected: "abc  [>                             ] 0B/4.0kB  0B/s  ETA -",
+		},
+		{
+			name:     "half",
+			bar:      ProgressBar{name: "abc", total: 4000, current: 2000, started: started},
+			now:      started.Add(2 * time.Second),
+			expected: "abc  [===============>              ] 2.0kB/4.0kB  1.0kB/s  ETA 2s",
+		},
+		{
+			name:     "unknown size",
+			bar:      ProgressBar{name: "abc", current: 2000, started: started},
+			now:      started.Add(time.Second),
+			expected: "abc  [==============================] 2.0kB/0B  2.0kB/s  ETA -",
+		},
+		{
+			name: "done",
+			bar: ProgressBar{
+				name: "abc", total: 4000, current: 4000, state: barDone,
+				started: started, ended: started.Add(1500 * time.Millisecond),
+			},
+			now:      started.Add(time.Hour),
+			expected: "abc  Done  4.0kB in 1.5s",
+		},
+		{
+			name:     "skipped",
+			bar:      ProgressBar{name: "abc", total: 4000, started: started, state: barSkipped},
+			now:      started,
+			expected: "abc  Already exists",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.bar.render(tc.now))
+		})
+	}
+}
+
+func TestProgressTerminalRedrawsBars(t *testing.T) {
+	out := &bytes.Buffer{}
+	transfers := &transfers{out: out, tty: true}
+
+	first := transfers.add("first", 10)
+	second := transfers.add("second", 10)
+
+	out.Reset()
+	first.Done()
+
+	// the cursor moves up over both bars, which are redrawn in place.
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "\x1b[2A\x1b[2Kfirst  Done"), lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "\x1b[2Ksecond  ["), lines[1])
+
+	second.Skip()
+
+	// once every transfer finished, the next one starts below them.
+	out.Reset()
+	transfers.add("third", 10)
+	assert.True(t, strings.HasPrefix(out.String(), "\x1b[2Kthird  ["), out.String())
+}
+
+func TestProgressWithoutTerminal(t *testing.T) {
+	errOut := &bytes.Buffer{}
+	ui := NewUIWithOutputErrorAndInput(&bytes.Buffer{}, errOut, nil)
+
+	bar := ui.Progress("abc", 4000)
+	bar.Add(1000)
+	bar.Done()
+
+	ui.Progress("def", 10).Skip()
+
+	lines := strings.Split(strings.TrimSuffix(errOut.String(), "\n"), "\n")
+	require.Len(t, lines, 4, errOut.String())
+
+	// transfers are logged when they start and end, updates in between only once per log interval.
+	assert.Equal(t, "abc: 0B/4.0kB (0%) 0B/s ETA -", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "abc: done 1.0kB in "), lines[1])
+	assert.Equal(t, "def: 0B/10B (0%) 0B/s ETA -", lines[2])
+	assert.Equal(t, "def: already exists", lines[3])
+	assert.NotContains(t, errOut.String(), "\x1b[")
+}
+
+func TestProgressQuiet(t *testing.T) {
+	errOut := &bytes.Buffer{}
+	ui := NewUIWithOutputErrorAndInput(&bytes.Buffer{}, errOut, nil)
+	ui.SetQuiet(true)
+
+	bar := ui.Progress("abc", 10)
+	assert.Nil(t, bar)
+
+	bar.Add(10)
+	bar.Done()
+	bar.Skip()
+
+	assert.Empty(t, errOut.String())
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"os"
 	"runtime"
+	"sync"
 
 	"github.com/fatih/color"
 )
@@ -20,7 +21,6 @@ const (
 	problem
 	note
 	success
-	progress
 )
 
 const (
@@ -42,6 +42,10 @@ type UI struct {
 	output io.Writer
 	err    io.Writer
 	input  io.Reader
+	quiet  bool
+
+	transfersOnce sync.Once
+	transfers     *transfers
 }
 
 // Message represents a piece of information we want displayed to the user.
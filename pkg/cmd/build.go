@@ -1,22 +1,39 @@
 package cmd
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/opcr-io/policy/internal/runtime"
+	"github.com/opcr-io/policy/pkg/app"
 	perr "github.com/opcr-io/policy/pkg/errors"
+	"github.com/pkg/errors"
 )
 
 //nolint:lll
 type BuildCmd struct {
-	Tag                string            `name:"tag" short:"t" help:"Name and optionally a tag in the 'name:tag' format, if not provided it will be 'default:latest'"`
+	Tags               []string          `name:"tag" short:"t" help:"Name and optionally a tag in the 'name:tag' format, repeat to apply several tags, if not provided it will be 'default:latest'"`
+	Push               bool              `name:"push" help:"Push the image and all of its tags after a successful build."`
+	Watch              bool              `name:"watch" short:"w" help:"Rebuild and retag the policy whenever its sources change."`
+	NoCache            bool              `name:"no-cache" help:"Compile the sources even when an image was built before from the same inputs."`
+	Lint               bool              `name:"lint" help:"Lint the sources first and do not create the image when there are violations."`
+	LintDisable        []string          `name:"lint-disable" help:"Lint rules not applied with --lint."`
+	Schema             string            `name:"schema" short:"s" help:"Path to a JSON schema file for the input, or a directory of schemas, to type-check the policy against and store with the image." type:"path"`
+	Docs               bool              `name:"docs" help:"Record the documentation summary of the packages, their titles and entrypoints, on the image."`
+	Created            string            `name:"created" env:"SOURCE_DATE_EPOCH" help:"Creation time recorded on the image, as RFC 3339 or seconds since the Unix epoch, for reproducible builds."`
 	Path               []string          `name:"path" arg:"" help:"Path to the policy sources." type:"string"`
 	Annotations        map[string]string `name:"annotations" short:"a" help:"Annotations to apply to the policy." type:"string:string"`
-	RunConfigFile      string            `name:"build-config-file" help:"Set path of configuration file."`
-	Target             string            `name:"target" default:"rego" help:"Set the output bundle target type."`
+	RunConfigFile      string            `name:"build-config-file" hidden:"" help:"Deprecated, has no effect."`
+	Target             string            `name:"target" default:"rego" enum:"rego" help:"Set the output bundle target type."`
 	OptimizationLevel  int               `name:"optimize" short:"O" default:"0" help:"Set optimization level."`
 	Entrypoints        []string          `name:"entrypoint" short:"e" help:"Set slash separated entrypoint path."`
 	Revision           string            `name:"revision" short:"r" help:"Set output bundle revision."`
 	Ignore             []string          `name:"ignore" help:"Set file and directory names to ignore during loading (e.g., '.*' excludes hidden files)."`
 	Capabilities       string            `name:"capabilities" help:"Set capabilities.json file path."`
+	Test               bool              `name:"test" help:"Run the Rego tests of the sources and refuse to build the image when any fails."`
+	MinCoverage        float64           `name:"min-coverage" help:"Minimum line coverage of the tests in percent, implies --test."`
+	OPAVersions        []string          `name:"opa-versions" help:"OPA versions to check the policy against, the lowest compatible one is recorded as an annotation."`
+	CapabilityProfile  string            `name:"capability-profile" help:"Capability profile of the config to build with, by default the one required for the tags."`
 	VerificationKey    string            `name:"verification-key" help:"Set the secret (HMAC) or path of the PEM file containing the public key (RSA and ECDSA)."`
 	VerificationKeyID  string            `name:"verification-key-id" default:"default" help:"Name assigned to the verification key used for bundle verification."`
 	Algorithm          string            `name:"signing-alg" default:"RS256" help:"Name of the signing algorithm."`
@@ -33,26 +50,51 @@ func (c *BuildCmd) Run(g *Globals) error {
 		return perr.ErrBuildFailed.WithMessage("rego version %s", regoVersion.String())
 	}
 
-	err := g.App.Build(
-		c.Tag,
-		c.Path,
-		c.Annotations,
-		c.RunConfigFile,
-		c.Target,
-		c.OptimizationLevel,
-		c.Entrypoints,
-		c.Revision,
-		c.Ignore,
-		c.Capabilities,
-		c.VerificationKey,
-		c.VerificationKeyID,
-		c.Algorithm,
-		c.Scope,
-		c.ExcludeVerifyFiles,
-		c.SigningKey,
-		c.ClaimsFile,
-		regoVersion,
-	)
+	created, err := parseTime("creation time", c.Created)
+	if err != nil {
+		return perr.ErrBuildFailed.WithError(err)
+	}
+
+	if c.RunConfigFile != "" {
+		g.App.UI.Exclamation().Msg("The --build-config-file flag is deprecated and has no effect.")
+	}
+
+	opts := &app.BuildOptions{
+		Tags:                 c.Tags,
+		Paths:                c.Path,
+		Annotations:          c.Annotations,
+		Push:                 c.Push,
+		NoCache:              c.NoCache,
+		Lint:                 c.Lint,
+		LintDisable:          c.LintDisable,
+		Docs:                 c.Docs,
+		Schema:               c.Schema,
+		Created:              created,
+		CapabilitiesJSONFile: c.Capabilities,
+		CapabilityProfile:    c.CapabilityProfile,
+		OPAVersions:          c.OPAVersions,
+		Test:                 c.Test,
+		MinCoverage:          c.MinCoverage,
+		OptimizationLevel:    c.OptimizationLevel,
+		Entrypoints:          c.Entrypoints,
+		Revision:             c.Revision,
+		Ignore:               c.Ignore,
+		Algorithm:            c.Algorithm,
+		Key:                  c.SigningKey,
+		Scope:                c.Scope,
+		PubKey:               c.VerificationKey,
+		PubKeyID:             c.VerificationKeyID,
+		ClaimsFile:           c.ClaimsFile,
+		ExcludeVerifyFiles:   c.ExcludeVerifyFiles,
+		RegoVersion:          regoVersion,
+	}
+
+	if c.Watch {
+		err = g.App.BuildWatch(opts)
+	} else {
+		err = g.App.Build(opts)
+	}
+
 	if err != nil {
 		return perr.ErrBuildFailed.WithError(err)
 	}
@@ -61,3 +103,22 @@ func (c *BuildCmd) Run(g *Globals) error {
 
 	return nil
 }
+
+// parseTime accepts an RFC 3339 timestamp or, as SOURCE_DATE_EPOCH, a number of seconds since the Unix epoch.
+// name describes the time in errors.
+func parseTime(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid %s [%s], expected RFC 3339 or seconds since the Unix epoch", name, value)
+	}
+
+	return t, nil
+}
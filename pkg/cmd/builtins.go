@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/opcr-io/policy/internal/runtime"
+	"github.com/opcr-io/policy/pkg/app"
+	"github.com/opcr-io/policy/pkg/errors"
+)
+
+type BuiltinsCmd struct {
+	Scan BuiltinsScanCmd `name:"scan" cmd:"" help:"Find undefined functions and declare them as required builtins in the .manifest."`
+}
+
+type BuiltinsScanCmd struct {
+	Paths        []string `name:"path" arg:"" help:"Path to the policy sources." default:"."`
+	Ignore       []string `name:"ignore" help:"Set file and directory names to ignore during loading (e.g., '.*' excludes hidden files)."`
+	Capabilities string   `name:"capabilities" help:"Set capabilities.json file path."`
+	RegoVersion  string   `name:"rego-version" enum:"rego.v0, rego.v1, rego.v0v1" default:"rego.v1" help:"Set rego version flag (enum: rego.v0, rego.v0v1, rego.v1)."`
+	Manifest     string   `name:"manifest" type:"path" help:"Manifest to update (defaults to the .manifest of the first path)."`
+	DryRun       bool     `name:"dry-run" help:"Report undefined functions without updating the manifest."`
+}
+
+func (c *BuiltinsScanCmd) Run(g *Globals) error {
+	err := g.App.BuiltinsScan(&app.BuiltinsScanOptions{
+		Paths:                c.Paths,
+		Ignore:               c.Ignore,
+		CapabilitiesJSONFile: c.Capabilities,
+		RegoVersion:          runtime.RegoVersionFromString(c.RegoVersion),
+		Manifest:             c.Manifest,
+		DryRun:               c.DryRun,
+	})
+	if err != nil {
+		return errors.ErrBuiltinsFailed.WithError(err)
+	}
+
+	<-g.App.Context.Done()
+
+	return nil
+}
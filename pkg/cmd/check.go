@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"github.com/opcr-io/policy/internal/runtime"
+	"github.com/opcr-io/policy/pkg/app"
+	"github.com/opcr-io/policy/pkg/errors"
+)
+
+type CheckCmd struct {
+	Paths       []string `name:"path" arg:"" help:"Path to the policy sources, or the policy to check." default:"."`
+	OPAVersions []string `name:"opa-versions" required:"" help:"OPA versions to check the policy against (e.g., 0.68,1.0,1.4)."`
+	Ignore      []string `name:"ignore" help:"Set file and directory names to ignore during loading (e.g., '.*' excludes hidden files)."`
+	RegoVersion string   `name:"rego-version" enum:"rego.v0, rego.v1, rego.v0v1" default:"rego.v1" help:"Set rego version flag (enum: rego.v0, rego.v0v1, rego.v1)."`
+}
+
+func (c *CheckCmd) Run(g *Globals) error {
+	err := g.App.Check(&app.CheckOptions{
+		Paths:       c.Paths,
+		Ignore:      c.Ignore,
+		RegoVersion: runtime.RegoVersionFromString(c.RegoVersion),
+		OPAVersions: c.OPAVersions,
+	})
+	if err != nil {
+		return errors.ErrCheckFailed.WithError(err)
+	}
+
+	<-g.App.Context.Done()
+
+	return nil
+}
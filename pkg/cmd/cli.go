@@ -12,6 +12,8 @@ import (
 	"github.com/opcr-io/policy/internal/logger"
 	"github.com/opcr-io/policy/pkg/app"
 	"github.com/opcr-io/policy/pkg/cc/config"
+	"github.com/opcr-io/policy/pkg/clui"
+	"github.com/opcr-io/policy/pkg/output"
 )
 
 const (
@@ -101,6 +103,8 @@ type Globals struct {
 	Verbosity int
 	Insecure  bool
 	Plaintext bool
+	Quiet     bool
+	Output    *output.Printer
 	App       *app.PolicyApp
 }
 
@@ -110,6 +114,8 @@ type CLI struct {
 	Verbosity int          `flag:"" short:"v" type:"counter" help:"Use to increase output verbosity."`
 	Insecure  bool         `flag:"" short:"k" help:"Do not verify TLS connections."`
 	Plaintext bool         `flag:"" help:"Use HTTP instead of HTTPS to connect to a registry."`
+	Quiet     bool         `flag:"" short:"q" help:"Do not report transfer progress."`
+	Format    string       `flag:"" help:"Output format: table, json, yaml, sarif (lint only) or a Go template (e.g. '{{.Digest}}')." default:"table"`
 	Build     BuildCmd     `cmd:"" help:"Build policies."`
 	Images    ImagesCmd    `cmd:"" help:"List policy images."`
 	Push      PushCmd      `cmd:"" help:"Push policies to a registry."`
@@ -117,11 +123,22 @@ type CLI struct {
 	Login     LoginCmd     `cmd:"" help:"Login to a registry."`
 	Logout    LogoutCmd    `cmd:"" help:"Logout from a registry."`
 	Save      SaveCmd      `cmd:"" help:"Save a policy to a local bundle tarball."`
+	Unpack    UnpackCmd    `cmd:"" help:"Extract the bundle of a policy to a directory."`
 	Tag       TagCmd       `cmd:"" help:"Create a new tag for an existing policy."`
 	Rm        RmCmd        `cmd:"" help:"Removes a policy from the local registry."`
 	Inspect   InspectCmd   `cmd:"" help:"Displays information about a policy."`
+	Diff      DiffCmd      `cmd:"" help:"Shows the differences between two policies."`
 	Repl      ReplCmd      `cmd:"" help:"Sets you up with a shell for running queries using an OPA instance with a policy loaded."`
+	Replay    ReplayCmd    `cmd:"" help:"Compares the decisions of two policies for recorded inputs."`
+	Eval      EvalCmd      `cmd:"" help:"Evaluates a query against a policy."`
+	Test      TestCmd      `cmd:"" help:"Runs the Rego tests of policy sources."`
 	Templates TemplatesCmd `cmd:"" help:"List and apply templates"`
+	Builtins  BuiltinsCmd  `cmd:"" help:"Manage the required builtins of policies."`
+	Migrate   MigrateCmd   `cmd:"" help:"Rewrite policy sources for a newer Rego version."`
+	Fmt       FmtCmd       `cmd:"" help:"Format policy sources."`
+	Lint      LintCmd      `cmd:"" help:"Lint policy sources."`
+	Doc       DocCmd       `cmd:"" help:"Render the documentation of policy sources or of a policy."`
+	Check     CheckCmd     `cmd:"" help:"Check that policy sources or a policy can be loaded by versions of OPA."`
 	Version   VersionCmd   `cmd:"" help:"Prints version information."`
 }
 
@@ -153,6 +170,16 @@ This might be a bug. Please open an issue here: https://github.com/opcr-io/polic
 			err)
 	}
 
+	if policyAPP != nil {
+		// structured documents own stdout, human messages move to stderr.
+		if g.Output.Structured() {
+			policyAPP.UI = clui.NewUIWithOutput(policyAPP.UI.Err())
+		}
+
+		policyAPP.UI.SetQuiet(g.Quiet)
+		policyAPP.Output = g.Output
+	}
+
 	g.App = policyAPP
 
 	return cleanup
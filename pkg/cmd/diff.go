@@ -0,0 +1,19 @@
+package cmd
+
+import "github.com/opcr-io/policy/pkg/errors"
+
+type DiffCmd struct {
+	From string `name:"from" arg:"" help:"Policy to compare from."`
+	To   string `name:"to" arg:"" help:"Policy to compare to."`
+}
+
+func (c *DiffCmd) Run(g *Globals) error {
+	err := g.App.Diff(c.From, c.To)
+	if err != nil {
+		return errors.ErrDiffFailed.WithError(err)
+	}
+
+	<-g.App.Context.Done()
+
+	return nil
+}
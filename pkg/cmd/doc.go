@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/opcr-io/policy/internal/runtime"
+	"github.com/opcr-io/policy/pkg/app"
+	"github.com/opcr-io/policy/pkg/errors"
+)
+
+type DocCmd struct {
+	Paths       []string `name:"path" arg:"" help:"Path to the policy sources, or the policy to document." default:"."`
+	HTML        bool     `name:"html" help:"Render an HTML page instead of Markdown."`
+	Output      string   `name:"output" short:"o" help:"File the documentation is written to, stdout by default."`
+	Ignore      []string `name:"ignore" help:"Set file and directory names to ignore during loading (e.g., '.*' excludes hidden files)."`
+	RegoVersion string   `name:"rego-version" enum:"rego.v0, rego.v1, rego.v0v1" default:"rego.v1" help:"Set rego version flag (enum: rego.v0, rego.v0v1, rego.v1)."`
+}
+
+func (c *DocCmd) Run(g *Globals) error {
+	err := g.App.Doc(&app.DocOptions{
+		Paths:       c.Paths,
+		Ignore:      c.Ignore,
+		RegoVersion: runtime.RegoVersionFromString(c.RegoVersion),
+		HTML:        c.HTML,
+		Output:      c.Output,
+	})
+	if err != nil {
+		return errors.ErrDocFailed.WithError(err)
+	}
+
+	<-g.App.Context.Done()
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/opcr-io/policy/internal/runtime"
+	"github.com/opcr-io/policy/pkg/app"
+	"github.com/opcr-io/policy/pkg/errors"
+)
+
+type EvalCmd struct {
+	Policy      string `name:"policy" arg:"" help:"Policy to evaluate."`
+	Query       string `name:"query" arg:"" optional:"" help:"Rego query to evaluate, e.g. 'data.authz.allowed', the entrypoint of the policy by default."`
+	Input       string `name:"input" short:"i" help:"Path to a JSON file used as input document." type:"existingfile"`
+	Mocks       string `name:"mocks" help:"Path to a YAML or JSON file with the responses of stub builtins." type:"existingfile"`
+	RegoVersion string `name:"rego-version" enum:"rego.v0, rego.v1, rego.v0v1" default:"rego.v1" help:"Set rego version flag (enum: rego.v0, rego.v0v1, rego.v1)."`
+	Schema      string `name:"schema" short:"s" help:"Path to a JSON schema file for the input, or a directory of schemas, to type-check against instead of the schemas of the policy." type:"path"`
+}
+
+func (c *EvalCmd) Run(g *Globals) error {
+	var input any
+
+	if c.Input != "" {
+		buf, err := os.ReadFile(c.Input)
+		if err != nil {
+			return errors.ErrEvalFailed.WithError(err)
+		}
+
+		if err := json.Unmarshal(buf, &input); err != nil {
+			return errors.ErrEvalFailed.WithMessage("failed to parse input file [%s]", c.Input).WithError(err)
+		}
+	}
+
+	mocks, err := loadMocks(c.Mocks, runtime.RegoVersionFromString(c.RegoVersion))
+	if err != nil {
+		return errors.ErrEvalFailed.WithError(err)
+	}
+
+	err = g.App.Eval(&app.EvalOptions{
+		Reference: c.Policy,
+		Query:     c.Query,
+		Input:     input,
+		Mocks:     mocks,
+		Schema:    c.Schema,
+	})
+	if err != nil {
+		return errors.ErrEvalFailed.WithError(err)
+	}
+
+	<-g.App.Context.Done()
+
+	return nil
+}
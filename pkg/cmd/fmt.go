@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/opcr-io/policy/internal/runtime"
+	"github.com/opcr-io/policy/pkg/app"
+	"github.com/opcr-io/policy/pkg/errors"
+)
+
+type FmtCmd struct {
+	Paths       []string `name:"path" arg:"" help:"Path to the policy sources." default:"."`
+	Check       bool     `name:"check" help:"List the files that are not formatted without writing them, fail when there are any."`
+	Diff        bool     `name:"diff" short:"d" help:"Print the changes formatting makes without writing them."`
+	Ignore      []string `name:"ignore" help:"Set file and directory names to ignore during loading (e.g., '.*' excludes hidden files)."`
+	RegoVersion string   `name:"rego-version" enum:"rego.v0, rego.v1, rego.v0v1" default:"rego.v1" help:"Set rego version flag (enum: rego.v0, rego.v0v1, rego.v1)."`
+}
+
+func (c *FmtCmd) Run(g *Globals) error {
+	err := g.App.Fmt(&app.FmtOptions{
+		Paths:       c.Paths,
+		Ignore:      c.Ignore,
+		RegoVersion: runtime.RegoVersionFromString(c.RegoVersion),
+		Check:       c.Check,
+		Diff:        c.Diff,
+	})
+	if err != nil {
+		return errors.ErrFmtFailed.WithError(err)
+	}
+
+	<-g.App.Context.Done()
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/opcr-io/policy/internal/runtime"
+	"github.com/opcr-io/policy/pkg/app"
+	"github.com/opcr-io/policy/pkg/errors"
+)
+
+type LintCmd struct {
+	Paths        []string `name:"path" arg:"" help:"Path to the policy sources." default:"."`
+	Disable      []string `name:"disable" help:"Lint rules not to apply (unused-import, unused-variable, shadowed-rule, deprecated-builtin, missing-metadata)."`
+	Ignore       []string `name:"ignore" help:"Set file and directory names to ignore during loading (e.g., '.*' excludes hidden files)."`
+	Capabilities string   `name:"capabilities" help:"Set capabilities.json file path."`
+	RegoVersion  string   `name:"rego-version" enum:"rego.v0, rego.v1, rego.v0v1" default:"rego.v1" help:"Set rego version flag (enum: rego.v0, rego.v0v1, rego.v1)."`
+}
+
+func (c *LintCmd) Run(g *Globals) error {
+	err := g.App.Lint(&app.LintOptions{
+		Paths:                c.Paths,
+		Ignore:               c.Ignore,
+		CapabilitiesJSONFile: c.Capabilities,
+		RegoVersion:          runtime.RegoVersionFromString(c.RegoVersion),
+		Disable:              c.Disable,
+	})
+	if err != nil {
+		return errors.ErrLintFailed.WithError(err)
+	}
+
+	<-g.App.Context.Done()
+
+	return nil
+}
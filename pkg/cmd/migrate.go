@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/opcr-io/policy/internal/runtime"
+	"github.com/opcr-io/policy/pkg/app"
+	"github.com/opcr-io/policy/pkg/errors"
+)
+
+type MigrateCmd struct {
+	Paths        []string `name:"path" arg:"" help:"Path to the policy sources." default:"."`
+	To           string   `name:"to" enum:"rego.v1" default:"rego.v1" help:"Rego version to migrate to (enum: rego.v1)."`
+	Check        bool     `name:"check" help:"Report the modules that would change without writing them, fail when there are any."`
+	Ignore       []string `name:"ignore" help:"Set file and directory names to ignore during loading (e.g., '.*' excludes hidden files)."`
+	Capabilities string   `name:"capabilities" help:"Set capabilities.json file path."`
+}
+
+func (c *MigrateCmd) Run(g *Globals) error {
+	err := g.App.Migrate(&app.MigrateOptions{
+		Paths:                c.Paths,
+		To:                   runtime.RegoVersionFromString(c.To),
+		Ignore:               c.Ignore,
+		CapabilitiesJSONFile: c.Capabilities,
+		Check:                c.Check,
+	})
+	if err != nil {
+		return errors.ErrMigrateFailed.WithError(err)
+	}
+
+	<-g.App.Context.Done()
+
+	return nil
+}
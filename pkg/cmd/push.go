@@ -3,14 +3,15 @@ package cmd
 import "github.com/pkg/errors"
 
 type PushCmd struct {
-	Policies []string `name:"policy" arg:"" help:"Policies to push."`
+	Policies  []string `name:"policy" arg:"" help:"Policies to push."`
+	MountFrom []string `name:"mount-from" help:"Repository in the same registry to mount existing blobs from instead of uploading them, in addition to the mount_from repositories of the config."`
 }
 
 func (c *PushCmd) Run(g *Globals) error {
 	var errs error
 
 	for _, policyRef := range c.Policies {
-		err := g.App.Push(policyRef)
+		err := g.App.Push(policyRef, c.MountFrom)
 		if err != nil {
 			g.App.UI.Problem().WithErr(err).Msgf("Failed to push policy: %s", policyRef)
 			errs = err
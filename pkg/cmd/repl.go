@@ -1,15 +1,24 @@
 package cmd
 
-import "github.com/pkg/errors"
+import (
+	"github.com/opcr-io/policy/internal/runtime"
+	"github.com/pkg/errors"
+)
 
 type ReplCmd struct {
-	Policy    string `name:"policy" arg:"" help:"Policy to run." type:"string"`
-	MaxErrors int    `name:"max-errors" short:"m" help:"Set the number of errors to allow before compilation fails early." default:"10"`
+	Policy      string `name:"policy" arg:"" help:"Policy to run." type:"string"`
+	MaxErrors   int    `name:"max-errors" short:"m" help:"Set the number of errors to allow before compilation fails early." default:"10"`
+	Mocks       string `name:"mocks" help:"Path to a YAML or JSON file with the responses of stub builtins." type:"existingfile"`
+	RegoVersion string `name:"rego-version" enum:"rego.v0, rego.v1, rego.v0v1" default:"rego.v1" help:"Set rego version flag (enum: rego.v0, rego.v0v1, rego.v1)."`
 }
 
 func (c *ReplCmd) Run(g *Globals) error {
-	err := g.App.Repl(c.Policy, c.MaxErrors)
+	mocks, err := loadMocks(c.Mocks, runtime.RegoVersionFromString(c.RegoVersion))
 	if err != nil {
+		return errors.Wrap(err, "failed to load mocks")
+	}
+
+	if err := g.App.Repl(c.Policy, c.MaxErrors, mocks); err != nil {
 		return errors.Wrap(err, "there was an error running the OPA runtime")
 	}
 
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/opcr-io/policy/internal/runtime"
+	"github.com/opcr-io/policy/pkg/app"
+	"github.com/opcr-io/policy/pkg/errors"
+)
+
+type ReplayCmd struct {
+	From          string   `name:"from" required:"" help:"Policy the decisions are compared from."`
+	To            string   `name:"to" required:"" help:"Policy the decisions are compared to."`
+	Inputs        string   `name:"inputs" required:"" help:"Directory of JSON input files, or file of newline-delimited inputs or OPA decision log events."`
+	Query         string   `name:"query" required:"" help:"Rego query to evaluate, e.g. 'data.authz.allowed'."`
+	ExpectChanges []string `name:"expect-change" help:"Pattern of the input IDs whose decisions are expected to change, repeat for several."`
+	Mocks         string   `name:"mocks" help:"Path to a YAML or JSON file with the responses of stub builtins." type:"existingfile"`
+	RegoVersion   string   `name:"rego-version" enum:"rego.v0, rego.v1, rego.v0v1" default:"rego.v1" help:"Set rego version flag (enum: rego.v0, rego.v0v1, rego.v1)."`
+}
+
+func (c *ReplayCmd) Run(g *Globals) error {
+	mocks, err := loadMocks(c.Mocks, runtime.RegoVersionFromString(c.RegoVersion))
+	if err != nil {
+		return errors.ErrReplayFailed.WithError(err)
+	}
+
+	err = g.App.Replay(&app.ReplayOptions{
+		From:          c.From,
+		To:            c.To,
+		Query:         c.Query,
+		Inputs:        c.Inputs,
+		ExpectChanges: c.ExpectChanges,
+		Mocks:         mocks,
+	})
+	if err != nil {
+		return errors.ErrReplayFailed.WithError(err)
+	}
+
+	<-g.App.Context.Done()
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"github.com/opcr-io/policy/internal/runtime"
+	"github.com/opcr-io/policy/pkg/app"
+	"github.com/opcr-io/policy/pkg/errors"
+)
+
+type TestCmd struct {
+	Paths             []string `name:"path" arg:"" help:"Path to the policy sources and tests." default:"."`
+	Filter            string   `name:"run" short:"r" help:"Run only the tests matching this regular expression."`
+	Verbose           bool     `name:"verbose" help:"Report every test, with its print output."`
+	Ignore            []string `name:"ignore" help:"Set file and directory names to ignore during loading (e.g., '.*' excludes hidden files)."`
+	Capabilities      string   `name:"capabilities" help:"Set capabilities.json file path."`
+	RegoVersion       string   `name:"rego-version" enum:"rego.v0, rego.v1, rego.v0v1" default:"rego.v1" help:"Set rego version flag (enum: rego.v0, rego.v0v1, rego.v1)."`
+	Mocks             string   `name:"mocks" help:"Path to a YAML or JSON file with the responses of stub builtins." type:"existingfile"`
+	Schema            string   `name:"schema" short:"s" help:"Path to a JSON schema file for the input, or a directory of schemas, to type-check the policy against." type:"path"`
+	Coverage          bool     `name:"coverage" help:"Report the line coverage of the modules other than tests."`
+	CoverageFile      string   `name:"coverage-output" help:"Write the coverage report to this file, implies --coverage."`
+	CoverageFormat    string   `name:"coverage-format" enum:"json,lcov,cobertura" default:"json" help:"Format of the coverage report (enum: json, lcov, cobertura)."`
+	MinCoverage       float64  `name:"min-coverage" help:"Fail when the line coverage in percent is below this threshold, implies --coverage."`
+	CoverageTimestamp string   `name:"coverage-timestamp" env:"SOURCE_DATE_EPOCH" help:"Time of the run recorded in Cobertura reports, as RFC 3339 or seconds since the Unix epoch."`
+}
+
+func (c *TestCmd) Run(g *Globals) error {
+	regoVersion := runtime.RegoVersionFromString(c.RegoVersion)
+
+	mocks, err := loadMocks(c.Mocks, regoVersion)
+	if err != nil {
+		return errors.ErrTestFailed.WithError(err)
+	}
+
+	timestamp, err := parseTime("coverage timestamp", c.CoverageTimestamp)
+	if err != nil {
+		return errors.ErrTestFailed.WithError(err)
+	}
+
+	err = g.App.Test(&app.TestOptions{
+		Paths:                c.Paths,
+		Ignore:               c.Ignore,
+		CapabilitiesJSONFile: c.Capabilities,
+		RegoVersion:          regoVersion,
+		Run:                  c.Filter,
+		Mocks:                mocks,
+		Schema:               c.Schema,
+		Coverage:             c.Coverage,
+		CoverageFile:         c.CoverageFile,
+		CoverageFormat:       c.CoverageFormat,
+		MinCoverage:          c.MinCoverage,
+		CoverageTimestamp:    timestamp,
+	}, c.Verbose)
+	if err != nil {
+		return errors.ErrTestFailed.WithError(err)
+	}
+
+	<-g.App.Context.Done()
+
+	return nil
+}
+
+// loadMocks reads the mock file of a command, if any.
+func loadMocks(path string, regoVersion app.RegoVersion) (*app.Mocks, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	return app.LoadMocks(path, regoVersion)
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/opcr-io/policy/pkg/app"
+	"github.com/opcr-io/policy/pkg/errors"
+)
+
+//nolint:lll
+type UnpackCmd struct {
+	Policy             string   `name:"policy" arg:"" help:"Policy to unpack."`
+	Dir                string   `name:"dir" arg:"" help:"Directory the bundle files are written to, it must be missing or empty."`
+	VerificationKey    string   `name:"verification-key" help:"Verify the bundle signatures before unpacking with the secret (HMAC) or path of the PEM file containing the public key (RSA and ECDSA)."`
+	VerificationKeyID  string   `name:"verification-key-id" default:"default" help:"Name assigned to the verification key used for bundle verification."`
+	Algorithm          string   `name:"signing-alg" default:"RS256" help:"Name of the signing algorithm."`
+	Scope              string   `name:"scope" help:"Scope to use for bundle signature verification."`
+	ExcludeVerifyFiles []string `name:"exclude-files-verify" help:"Set file names to exclude during bundle verification."`
+}
+
+func (c *UnpackCmd) Run(g *Globals) error {
+	err := g.App.Unpack(&app.UnpackOptions{
+		Reference:          c.Policy,
+		Dir:                c.Dir,
+		PubKey:             c.VerificationKey,
+		PubKeyID:           c.VerificationKeyID,
+		Algorithm:          c.Algorithm,
+		Scope:              c.Scope,
+		ExcludeVerifyFiles: c.ExcludeVerifyFiles,
+	})
+	if err != nil {
+		return errors.ErrUnpackFailed.WithError(err)
+	}
+
+	<-g.App.Context.Done()
+
+	return nil
+}
@@ -7,6 +7,11 @@ type VersionCmd struct{}
 func (c *VersionCmd) Run(g *Globals) error {
 	v := version.GetInfo()
 
+	if g.App.Output.Structured() {
+		g.App.Cancel()
+		return g.App.Output.Print(&v)
+	}
+
 	g.App.UI.Normal().
 		WithStringValue("version", v.Version).
 		WithStringValue("date", v.Date).
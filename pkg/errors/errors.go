@@ -1,44 +1,225 @@
 package errors
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/containerd/containerd/v2/core/remotes/docker"
+	remoteerrors "github.com/containerd/containerd/v2/core/remotes/errors"
+	cerrdefs "github.com/containerd/errdefs"
+	"oras.land/oras-go/v2/errdef"
+)
+
+// Code classifies an error so that automation can tell failures apart.
+type Code string
+
+const (
+	CodeUnknown            Code = "unknown"
+	CodeNotFound           Code = "not_found"
+	CodeAuthFailed         Code = "auth_failed"
+	CodeNetwork            Code = "network"
+	CodeCompileError       Code = "compile_error"
+	CodeVerificationFailed Code = "verification_failed"
+	CodeUserCancelled      Code = "user_cancelled"
+)
+
+// Exit codes returned by the policy CLI for each error code.
+const (
+	ExitOK                 int = 0
+	ExitUnknown            int = 1
+	ExitNotFound           int = 3
+	ExitAuthFailed         int = 4
+	ExitNetwork            int = 5
+	ExitCompileError       int = 6
+	ExitVerificationFailed int = 7
+	ExitUserCancelled      int = 130
+)
+
+var exitCodes = map[Code]int{
+	CodeUnknown:            ExitUnknown,
+	CodeNotFound:           ExitNotFound,
+	CodeAuthFailed:         ExitAuthFailed,
+	CodeNetwork:            ExitNetwork,
+	CodeCompileError:       ExitCompileError,
+	CodeVerificationFailed: ExitVerificationFailed,
+	CodeUserCancelled:      ExitUserCancelled,
+}
 
 var (
-	ErrNotFound       = NewPolicyError("policy not found")
-	ErrBuildFailed    = NewPolicyError("build failed")
-	ErrLoginFailed    = NewPolicyError("login failed")
-	ErrLogoutFailed   = NewPolicyError("logout failed")
-	ErrImagesFailed   = NewPolicyError("list images failed")
-	ErrInspectFailed  = NewPolicyError("inspect failed")
-	ErrPullFailed     = NewPolicyError("pull failed")
-	ErrPushFailed     = NewPolicyError("push failed")
-	ErrSaveFailed     = NewPolicyError("save failed")
-	ErrReplFailed     = NewPolicyError("repl failed")
-	ErrTagFailed      = NewPolicyError("tag failed")
-	ErrTemplateFailed = NewPolicyError("template failed")
+	ErrNotFound           = NewPolicyErrorWithCode("policy not found", CodeNotFound)
+	ErrBuildFailed        = NewPolicyError("build failed")
+	ErrCompileFailed      = NewPolicyErrorWithCode("compile failed", CodeCompileError)
+	ErrVerificationFailed = NewPolicyErrorWithCode("verification failed", CodeVerificationFailed)
+	ErrAuthFailed         = NewPolicyErrorWithCode("authentication failed", CodeAuthFailed)
+	ErrNetwork            = NewPolicyErrorWithCode("registry unreachable", CodeNetwork)
+	ErrUserCancelled      = NewPolicyErrorWithCode("operation canceled by user", CodeUserCancelled)
+	ErrLoginFailed        = NewPolicyError("login failed")
+	ErrLogoutFailed       = NewPolicyError("logout failed")
+	ErrImagesFailed       = NewPolicyError("list images failed")
+	ErrInspectFailed      = NewPolicyError("inspect failed")
+	ErrPullFailed         = NewPolicyError("pull failed")
+	ErrPushFailed         = NewPolicyError("push failed")
+	ErrSaveFailed         = NewPolicyError("save failed")
+	ErrReplFailed         = NewPolicyError("repl failed")
+	ErrEvalFailed         = NewPolicyError("eval failed")
+	ErrTagFailed          = NewPolicyError("tag failed")
+	ErrTemplateFailed     = NewPolicyError("template failed")
+	ErrBuiltinsFailed     = NewPolicyError("builtins scan failed")
+	ErrTestFailed         = NewPolicyError("test failed")
+	ErrMigrateFailed      = NewPolicyError("migrate failed")
+	ErrFmtFailed          = NewPolicyError("fmt failed")
+	ErrLintFailed         = NewPolicyError("lint failed")
+	ErrDiffFailed         = NewPolicyError("diff failed")
+	ErrReplayFailed       = NewPolicyError("replay failed")
+	ErrUnpackFailed       = NewPolicyError("unpack failed")
+	ErrDocFailed          = NewPolicyError("doc failed")
+	ErrProfileViolated    = NewPolicyError("capability profile violated")
+	ErrCheckFailed        = NewPolicyError("check failed")
 )
 
+// PolicyCLIError is an error with a code, derived from one of the sentinel errors above.
+// errors.Is matches an error against the sentinel it was derived from,
+// and the wrapped cause remains reachable through errors.Unwrap.
 type PolicyCLIError struct {
 	Message string
+	Code    Code
+
+	base  *PolicyCLIError
+	cause error
 }
 
 func NewPolicyError(message string) *PolicyCLIError {
-	return &PolicyCLIError{Message: message}
+	return NewPolicyErrorWithCode(message, CodeUnknown)
+}
+
+func NewPolicyErrorWithCode(message string, code Code) *PolicyCLIError {
+	e := &PolicyCLIError{Message: message, Code: code}
+	e.base = e
+
+	return e
 }
 
 const arrow string = " -> "
 
 func (e *PolicyCLIError) Error() string {
+	if e.cause != nil {
+		return e.Message + arrow + e.cause.Error()
+	}
+
 	return e.Message
 }
 
+func (e *PolicyCLIError) Unwrap() error {
+	return e.cause
+}
+
+func (e *PolicyCLIError) Is(target error) bool {
+	t, ok := target.(*PolicyCLIError)
+
+	return ok && e.base == t.base
+}
+
 func (e *PolicyCLIError) WithMessage(message string, args ...any) *PolicyCLIError {
 	return &PolicyCLIError{
 		Message: e.Message + arrow + fmt.Sprintf(message, args...),
+		Code:    e.Code,
+		base:    e.base,
+		cause:   e.cause,
 	}
 }
 
 func (e *PolicyCLIError) WithError(base error) *PolicyCLIError {
+	if e.cause != nil {
+		base = fmt.Errorf("%w%s%w", e.cause, arrow, base)
+	}
+
 	return &PolicyCLIError{
-		Message: e.Message + arrow + base.Error(),
+		Message: e.Message,
+		Code:    e.Code,
+		base:    e.base,
+		cause:   base,
 	}
 }
+
+// WithRemoteError wraps the error of an exchange with a registry, failures to reach the registry, as refused
+// connections, unresolved hosts and timeouts, are wrapped in ErrNetwork.
+func (e *PolicyCLIError) WithRemoteError(err error) *PolicyCLIError {
+	if isNetwork(err) && !errors.Is(err, ErrNetwork) {
+		err = ErrNetwork.WithError(err)
+	}
+
+	return e.WithError(err)
+}
+
+func isNetwork(err error) bool {
+	var netErr net.Error
+
+	return errors.As(err, &netErr) || cerrdefs.IsUnavailable(err)
+}
+
+// CodeOf returns the code of the outermost classified error in the chain of err.
+// Errors that were not created from a sentinel are classified by their cause, e.g. containerd
+// not-found and unauthorized errors, network errors and context cancellation.
+func CodeOf(err error) Code {
+	if err == nil {
+		return CodeUnknown
+	}
+
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if pe, ok := e.(*PolicyCLIError); ok && pe.Code != CodeUnknown { //nolint:errorlint // walking the chain explicitly
+			return pe.Code
+		}
+
+		if joined, ok := e.(interface{ Unwrap() []error }); ok { //nolint:errorlint // walking the chain explicitly
+			for _, inner := range joined.Unwrap() {
+				if code := CodeOf(inner); code != CodeUnknown {
+					return code
+				}
+			}
+		}
+	}
+
+	var statusErr remoteerrors.ErrUnexpectedStatus
+
+	if errors.As(err, &statusErr) {
+		return codeOfStatus(statusErr.StatusCode)
+	}
+
+	switch {
+	case cerrdefs.IsNotFound(err), errors.Is(err, errdef.ErrNotFound):
+		return CodeNotFound
+	case cerrdefs.IsUnauthorized(err), cerrdefs.IsPermissionDenied(err), errors.Is(err, docker.ErrInvalidAuthorization):
+		return CodeAuthFailed
+	case errors.Is(err, context.Canceled):
+		return CodeUserCancelled
+	case isNetwork(err):
+		return CodeNetwork
+	default:
+		return CodeUnknown
+	}
+}
+
+func codeOfStatus(status int) Code {
+	switch {
+	case status == http.StatusNotFound:
+		return CodeNotFound
+	case status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return CodeAuthFailed
+	case status >= http.StatusInternalServerError:
+		return CodeNetwork
+	default:
+		return CodeUnknown
+	}
+}
+
+// ExitCode returns the documented process exit code for err.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	return exitCodes[CodeOf(err)]
+}
@@ -0,0 +1,74 @@
+package errors_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	cerrdefs "github.com/containerd/errdefs"
+	perr "github.com/opcr-io/policy/pkg/errors"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorsIs(t *testing.T) {
+	assert := assert.New(t)
+
+	cause := errors.New("boom")
+	err := errors.Wrap(perr.ErrBuildFailed.WithError(perr.ErrCompileFailed.WithError(cause)), "failed")
+
+	assert.ErrorIs(err, perr.ErrBuildFailed)
+	assert.ErrorIs(err, perr.ErrCompileFailed)
+	assert.ErrorIs(err, cause)
+	assert.NotErrorIs(err, perr.ErrNotFound)
+	assert.Equal("failed: build failed -> compile failed -> boom", err.Error())
+
+	var policyErr *perr.PolicyCLIError
+
+	assert.ErrorAs(err, &policyErr)
+	assert.Equal(perr.CodeUnknown, policyErr.Code)
+}
+
+func TestCodeOf(t *testing.T) {
+	tcs := []struct {
+		err  error
+		code perr.Code
+		exit int
+	}{
+		{nil, perr.CodeUnknown, perr.ExitOK},
+		{errors.New("boom"), perr.CodeUnknown, perr.ExitUnknown},
+		{perr.ErrNotFound.WithMessage("policy [%s] not in the local store", "x"), perr.CodeNotFound, perr.ExitNotFound},
+		{perr.ErrPushFailed.WithError(fmt.Errorf("resolve: %w", cerrdefs.ErrNotFound)), perr.CodeNotFound, perr.ExitNotFound},
+		{perr.ErrPullFailed.WithError(cerrdefs.ErrUnauthenticated), perr.CodeAuthFailed, perr.ExitAuthFailed},
+		{perr.ErrBuildFailed.WithError(perr.ErrCompileFailed.WithError(cerrdefs.ErrNotFound)), perr.CodeCompileError, perr.ExitCompileError},
+		{perr.ErrBuildFailed.WithError(perr.ErrVerificationFailed), perr.CodeVerificationFailed, perr.ExitVerificationFailed},
+		{errors.Wrap(context.Canceled, "interrupted"), perr.CodeUserCancelled, perr.ExitUserCancelled},
+		{perr.ErrUserCancelled, perr.CodeUserCancelled, perr.ExitUserCancelled},
+		{perr.ErrPushFailed.WithRemoteError(cerrdefs.ErrUnavailable), perr.CodeNetwork, perr.ExitNetwork},
+		{perr.ErrPullFailed.WithRemoteError(cerrdefs.ErrNotFound), perr.CodeNotFound, perr.ExitNotFound},
+	}
+
+	for i, tc := range tcs {
+		t.Run(fmt.Sprintf("tc:%03d", i), func(t *testing.T) {
+			assert.Equal(t, tc.code, perr.CodeOf(tc.err))
+			assert.Equal(t, tc.exit, perr.ExitCode(tc.err))
+		})
+	}
+}
+
+func TestWithRemoteError(t *testing.T) {
+	dial := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	err := perr.ErrPushFailed.WithRemoteError(fmt.Errorf("failed to do request: %w", dial))
+	assert.ErrorIs(t, err, perr.ErrPushFailed)
+	assert.ErrorIs(t, err, perr.ErrNetwork)
+	assert.Equal(t, "push failed -> registry unreachable -> failed to do request: dial tcp: connection refused", err.Error())
+
+	// network errors are wrapped once.
+	err = perr.ErrPullFailed.WithRemoteError(perr.ErrNetwork.WithError(dial))
+	assert.Equal(t, "pull failed -> registry unreachable -> dial tcp: connection refused", err.Error())
+
+	err = perr.ErrPullFailed.WithRemoteError(cerrdefs.ErrNotFound)
+	assert.NotErrorIs(t, err, perr.ErrNetwork)
+}
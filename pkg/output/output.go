@@ -0,0 +1,159 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+	FormatSARIF = "sarif"
+)
+
+type kind int
+
+const (
+	human kind = iota
+	jsonKind
+	yamlKind
+	sarifKind
+	templateKind
+)
+
+// Printer writes command results as machine-readable documents.
+// A nil Printer, or one created for the table format, selects human output.
+type Printer struct {
+	w       io.Writer
+	kind    kind
+	tmpl    *template.Template
+	printed int
+}
+
+// New creates a Printer writing to w in the given format:
+// "" or "table" for human output, "json", "yaml", "sarif" for the reports of findings, or a Go template such as
+// '{{.Digest}}'.
+func New(w io.Writer, format string) (*Printer, error) {
+	p := &Printer{w: w}
+
+	switch strings.TrimSpace(format) {
+	case "", FormatTable:
+		p.kind = human
+	case FormatJSON:
+		p.kind = jsonKind
+	case FormatYAML:
+		p.kind = yamlKind
+	case FormatSARIF:
+		p.kind = sarifKind
+	default:
+		tmpl, err := template.New("format").Funcs(sprig.TxtFuncMap()).Funcs(template.FuncMap{
+			"json": toJSON,
+		}).Parse(format)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid format template %q", format)
+		}
+
+		p.kind = templateKind
+		p.tmpl = tmpl
+	}
+
+	return p, nil
+}
+
+// Structured reports whether results must be printed as documents instead of human output.
+func (p *Printer) Structured() bool {
+	return p != nil && p.kind != human
+}
+
+// SARIF reports whether results must be printed as a SARIF log, which only commands reporting findings support.
+// The documents given to Print are then SARIF logs.
+func (p *Printer) SARIF() bool {
+	return p != nil && p.kind == sarifKind
+}
+
+// ErrorDocument is the structured form of a failed command.
+type ErrorDocument struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail describes the error that made a command fail.
+type ErrorDetail struct {
+	Message  string `json:"message"`
+	Code     string `json:"code"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// Print writes v as a single document. Templates are applied to every element when v is a slice.
+func (p *Printer) Print(v any) error {
+	if !p.Structured() {
+		return nil
+	}
+
+	p.printed++
+
+	switch {
+	case p.kind == jsonKind, p.kind == sarifKind:
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+
+		return enc.Encode(v)
+	case p.kind == yamlKind:
+		buf, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		// separate consecutive documents, e.g. one per pushed policy.
+		if p.printed > 1 {
+			buf = append([]byte("---\n"), buf...)
+		}
+
+		_, err = p.w.Write(buf)
+
+		return err
+	default:
+		return p.execute(v)
+	}
+}
+
+func (p *Printer) execute(v any) error {
+	items := []any{v}
+
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+		items = make([]any, rv.Len())
+		for i := range rv.Len() {
+			items[i] = rv.Index(i).Interface()
+		}
+	}
+
+	for _, item := range items {
+		if err := p.tmpl.Execute(p.w, item); err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintln(p.w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func toJSON(v any) (string, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return string(buf), nil
+}
@@ -13,9 +13,9 @@ var (
 
 // Info - version info.
 type Info struct {
-	Version string
-	Date    string
-	Commit  string
+	Version string `json:"version"`
+	Date    string `json:"date"`
+	Commit  string `json:"commit"`
 }
 
 // GetInfo gets version stamp information.
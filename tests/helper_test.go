@@ -83,7 +83,7 @@ func NewBuildCmd(t testing.TB, opts ...BuildOption) *cmd.BuildCmd {
 	t.Helper()
 
 	cmd := &cmd.BuildCmd{
-		Tag:                "",
+		Tags:               []string{},
 		Path:               []string{},
 		Annotations:        map[string]string{},
 		RunConfigFile:      "",
@@ -116,7 +116,7 @@ func BuildWithTag(tag string) BuildOption {
 			return errors.Errorf("tag cannot be empty")
 		}
 
-		cmd.Tag = tag
+		cmd.Tags = append(cmd.Tags, tag)
 
 		return nil
 	}